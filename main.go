@@ -53,13 +53,13 @@ func main() {
 		if proto&protoIPv4 == protoIPv4 {
 			tabs, err := netstat.UDPSocks(netstat.NoopFilter)
 			if err == nil {
-				displaySockInfo("udp", tabs)
+				displaySockInfo(tabs)
 			}
 		}
 		if proto&protoIPv6 == protoIPv6 {
 			tabs, err := netstat.UDP6Socks(netstat.NoopFilter)
 			if err == nil {
-				displaySockInfo("udp6", tabs)
+				displaySockInfo(tabs)
 			}
 		}
 	} else {
@@ -85,19 +85,19 @@ func main() {
 		if proto&protoIPv4 == protoIPv4 {
 			tabs, err := netstat.TCPSocks(fn)
 			if err == nil {
-				displaySockInfo("tcp", tabs)
+				displaySockInfo(tabs)
 			}
 		}
 		if proto&protoIPv6 == protoIPv6 {
 			tabs, err := netstat.TCP6Socks(fn)
 			if err == nil {
-				displaySockInfo("tcp6", tabs)
+				displaySockInfo(tabs)
 			}
 		}
 	}
 }
 
-func displaySockInfo(proto string, s []netstat.SockTabEntry) {
+func displaySockInfo(s []netstat.SockTabEntry) {
 	lookup := func(skaddr *netstat.SockAddr) string {
 		const IPv4Strlen = 17
 		addr := skaddr.IP.String()
@@ -120,6 +120,6 @@ func displaySockInfo(proto string, s []netstat.SockTabEntry) {
 		}
 		saddr := lookup(e.LocalAddr)
 		daddr := lookup(e.RemoteAddr)
-		fmt.Printf("%-5s %-23.23s %-23.23s %-12s %-16s\n", proto, saddr, daddr, e.State, p)
+		fmt.Printf("%-5s %-23.23s %-23.23s %-12s %-16s\n", e.Protocol, saddr, daddr, e.State, p)
 	}
 }
@@ -0,0 +1,284 @@
+package netstat
+
+import (
+	"net"
+	"sort"
+)
+
+// normIP returns the 4-byte form of IPv4 and IPv4-mapped IPv6 addresses and
+// ip itself otherwise
+func normIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// DistinctRemoteSubnets masks the remote address of each entry to prefixLen
+// bits and returns the distinct networks in the order they are first seen.
+// Entries without a peer, such as listeners, are skipped.
+// IPv4-mapped IPv6 addresses are treated as IPv4 ones. prefixLen is clamped
+// to the length of the address family, so that a single call with e.g. 64
+// yields /32 networks for IPv4 and /64 networks for IPv6 peers.
+func DistinctRemoteSubnets(s []SockTabEntry, prefixLen int) []*net.IPNet {
+	if prefixLen < 0 {
+		prefixLen = 0
+	}
+	seen := make(map[string]bool)
+	var nets []*net.IPNet
+	for _, e := range s {
+		if e.RemoteAddr == nil || e.RemoteAddr.IP.IsUnspecified() {
+			continue
+		}
+		ip := normIP(e.RemoteAddr.IP)
+		bits := len(ip) * 8
+		ones := prefixLen
+		if ones > bits {
+			ones = bits
+		}
+		mask := net.CIDRMask(ones, bits)
+		n := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		k := n.String()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// GroupByProcess groups entries by the PID of the process owning them.
+// Entries whose owner is unknown are grouped under PID 0.
+func GroupByProcess(s []SockTabEntry) map[int][]SockTabEntry {
+	m := make(map[int][]SockTabEntry)
+	for _, e := range s {
+		pid := 0
+		if e.Process != nil {
+			pid = e.Process.Pid
+		}
+		m[pid] = append(m[pid], e)
+	}
+	return m
+}
+
+// ProcessCount holds the number of sockets owned by a process
+type ProcessCount struct {
+	Process *Process
+	Count   int
+}
+
+// sortProcessCounts sorts pc by descending count, then ascending PID
+func sortProcessCounts(pc []ProcessCount) {
+	sort.Slice(pc, func(i, j int) bool {
+		if pc[i].Count != pc[j].Count {
+			return pc[i].Count > pc[j].Count
+		}
+		return pc[i].Process.Pid < pc[j].Process.Pid
+	})
+}
+
+// TopProcessesByConnections returns the n processes owning the most
+// entries, sorted by descending number of entries. Entries whose owner is
+// unknown are not ranked, as they may well belong to many processes.
+func TopProcessesByConnections(s []SockTabEntry, n int) []ProcessCount {
+	var pc []ProcessCount
+	for pid, g := range GroupByProcess(s) {
+		if pid == 0 {
+			continue
+		}
+		pc = append(pc, ProcessCount{Process: g[0].Process, Count: len(g)})
+	}
+	sortProcessCounts(pc)
+	if n >= 0 && len(pc) > n {
+		pc = pc[:n]
+	}
+	return pc
+}
+
+// DistinctRemotesPerProcess returns, for each process, the number of
+// distinct remote endpoints (address and port) it is connected to, by
+// descending count. An IPv4 peer reached over both an IPv4 and a dual
+// stack IPv6 socket is counted once. Listeners and entries whose owner is
+// unknown are left out.
+func DistinctRemotesPerProcess(s []SockTabEntry) []ProcessCount {
+	var pc []ProcessCount
+	for pid, g := range GroupByProcess(s) {
+		if pid == 0 {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, e := range g {
+			if e.RemoteAddr == nil || e.RemoteAddr.IP.IsUnspecified() {
+				continue
+			}
+			seen[addrKey(e.RemoteAddr)] = true
+		}
+		if len(seen) > 0 {
+			pc = append(pc, ProcessCount{Process: g[0].Process, Count: len(seen)})
+		}
+	}
+	sortProcessCounts(pc)
+	return pc
+}
+
+// ScanSuspect is a remote host whose connections look like a port scan
+type ScanSuspect struct {
+	IP         net.IP
+	LocalPorts int // distinct local ports connected to
+	SynRecv    int // connections in SYN_RECV state
+}
+
+// DetectScanners flags the remote hosts which connected to at least
+// minPorts distinct local ports, or which have at least minSynRecv half
+// open connections. A threshold which is not positive is disabled, so that
+// e.g. a minSynRecv of 0 flags port scans only. Only inbound connections,
+// as told by Role against the listeners found in s, are considered, so s
+// should be a full scan including the listening sockets. The suspects are
+// returned by decreasing number of local ports.
+func DetectScanners(s []SockTabEntry, minPorts, minSynRecv int) []ScanSuspect {
+	var listeners []SockTabEntry
+	for _, e := range s {
+		if isBound(&e) {
+			listeners = append(listeners, e)
+		}
+	}
+	type counts struct {
+		ip      net.IP
+		ports   map[uint16]bool
+		synRecv int
+	}
+	byIP := make(map[string]*counts)
+	for _, e := range s {
+		if e.RemoteAddr == nil || e.RemoteAddr.IP.IsUnspecified() {
+			continue
+		}
+		if e.State != SynRecv && e.Role(listeners) != RoleServer {
+			continue
+		}
+		ip := normIP(e.RemoteAddr.IP)
+		c := byIP[ip.String()]
+		if c == nil {
+			c = &counts{ip: ip, ports: make(map[uint16]bool)}
+			byIP[ip.String()] = c
+		}
+		c.ports[e.LocalAddr.Port] = true
+		if e.State == SynRecv {
+			c.synRecv++
+		}
+	}
+	var sus []ScanSuspect
+	for _, c := range byIP {
+		if minPorts > 0 && len(c.ports) >= minPorts || minSynRecv > 0 && c.synRecv >= minSynRecv {
+			sus = append(sus, ScanSuspect{IP: c.ip, LocalPorts: len(c.ports), SynRecv: c.synRecv})
+		}
+	}
+	sort.Slice(sus, func(i, j int) bool {
+		if sus[i].LocalPorts != sus[j].LocalPorts {
+			return sus[i].LocalPorts > sus[j].LocalPorts
+		}
+		return sus[i].SynRecv > sus[j].SynRecv
+	})
+	return sus
+}
+
+// IsClosing reports whether st is one of the states a TCP connection goes
+// through once either side has started shutting it down: FIN_WAIT1,
+// FIN_WAIT2, CLOSING, TIME_WAIT, CLOSE_WAIT and LAST_ACK
+func IsClosing(st SkState) bool {
+	switch st {
+	case FinWait1, FinWait2, Closing, TimeWait, CloseWait, LastAck:
+		return true
+	}
+	return false
+}
+
+// FilterClosing returns the entries of s in one of the closing states, see
+// IsClosing. A build up of such sockets, CLOSE_WAIT ones in particular,
+// usually means an application does not close its connections promptly.
+func FilterClosing(s []SockTabEntry) []SockTabEntry {
+	var c []SockTabEntry
+	for _, e := range s {
+		if IsClosing(e.State) {
+			c = append(c, e)
+		}
+	}
+	return c
+}
+
+// isBound reports whether e waits for incoming traffic: a TCP listener or
+// an unconnected UDP socket
+func isBound(e *SockTabEntry) bool {
+	if e.LocalAddr == nil {
+		return false
+	}
+	if transport(e.Protocol) == UDP {
+		return e.RemoteAddr == nil || e.RemoteAddr.IP.IsUnspecified() && e.RemoteAddr.Port == 0
+	}
+	return e.State == Listen
+}
+
+// BindOverlap describes a port bound both to a wildcard and to a specific
+// address of the same family
+type BindOverlap struct {
+	Protocol Protocol // TCP or UDP, regardless of the address family
+	Port     uint16
+	Wildcard []SockTabEntry
+	Specific []SockTabEntry
+}
+
+// OverlappingBinds finds the TCP listeners and UDP sockets bound to a
+// wildcard address (0.0.0.0 or ::) on a port some other socket is bound to
+// through a specific address covered by the wildcard, as in 0.0.0.0:80 and
+// 10.0.0.5:80. This is usually a configuration mistake since which socket
+// gets the traffic then depends on the destination address. The overlaps
+// are returned in ascending port order.
+func OverlappingBinds(s []SockTabEntry) []BindOverlap {
+	type key struct {
+		proto Protocol
+		port  uint16
+	}
+	groups := make(map[key]*BindOverlap)
+	var keys []key
+	for _, e := range s {
+		if !isBound(&e) {
+			continue
+		}
+		k := key{transport(e.Protocol), e.LocalAddr.Port}
+		g := groups[k]
+		if g == nil {
+			g = &BindOverlap{Protocol: k.proto, Port: k.port}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+		if e.LocalAddr.IP.IsUnspecified() {
+			g.Wildcard = append(g.Wildcard, e)
+		} else {
+			g.Specific = append(g.Specific, e)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].port != keys[j].port {
+			return keys[i].port < keys[j].port
+		}
+		return keys[i].proto < keys[j].proto
+	})
+	var overlaps []BindOverlap
+	for _, k := range keys {
+		g := groups[k]
+		var specific []SockTabEntry
+		for _, sp := range g.Specific {
+			for _, w := range g.Wildcard {
+				if bindCovers(w.LocalAddr, sp.LocalAddr) {
+					specific = append(specific, sp)
+					break
+				}
+			}
+		}
+		if len(specific) > 0 {
+			g.Specific = specific
+			overlaps = append(overlaps, *g)
+		}
+	}
+	return overlaps
+}
@@ -0,0 +1,43 @@
+package netstat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDetectScannersThresholds(t *testing.T) {
+	addr := func(ip string, port uint16) *SockAddr {
+		return &SockAddr{IP: net.ParseIP(ip), Port: port}
+	}
+	s := []SockTabEntry{
+		{Protocol: TCP, State: Listen, LocalAddr: addr("0.0.0.0", 80), RemoteAddr: addr("0.0.0.0", 0)},
+		{Protocol: TCP, State: Established, LocalAddr: addr("10.0.0.5", 80), RemoteAddr: addr("1.2.3.4", 40000)},
+		{Protocol: TCP, State: SynRecv, LocalAddr: addr("10.0.0.5", 80), RemoteAddr: addr("5.6.7.8", 40001)},
+		{Protocol: TCP, State: SynRecv, LocalAddr: addr("10.0.0.5", 80), RemoteAddr: addr("5.6.7.8", 40002)},
+	}
+	tests := []struct {
+		minPorts, minSynRecv int
+		want                 []string
+	}{
+		{50, 0, nil},
+		{0, 0, nil},
+		{-1, 2, []string{"5.6.7.8"}},
+		{1, 0, []string{"1.2.3.4", "5.6.7.8"}},
+	}
+	for _, tt := range tests {
+		sus := DetectScanners(s, tt.minPorts, tt.minSynRecv)
+		got := make(map[string]bool)
+		for _, v := range sus {
+			got[v.IP.String()] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("DetectScanners(%d, %d) = %v, want %v", tt.minPorts, tt.minSynRecv, sus, tt.want)
+			continue
+		}
+		for _, ip := range tt.want {
+			if !got[ip] {
+				t.Errorf("DetectScanners(%d, %d): %s not flagged", tt.minPorts, tt.minSynRecv, ip)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const pathARPTab = "/proc/net/arp"
+
+// atfCom is the flag of resolved ARP entries
+const atfCom = 0x02
+
+// columns of the /proc/net/arp:
+//
+//	IP address  HW type  Flags  HW address  Mask  Device
+const (
+	fieldARPIP    = 0
+	fieldARPFlags = 2
+	fieldARPHW    = 3
+	minARPFields  = 6
+)
+
+// parseARPTab returns the resolved entries of an ARP table, keyed by the
+// string form of their IPv4 address
+func parseARPTab(r io.Reader) (map[string]net.HardwareAddr, error) {
+	br := bufio.NewScanner(r)
+	m := make(map[string]net.HardwareAddr)
+
+	// Discard title
+	br.Scan()
+
+	for br.Scan() {
+		fields := strings.Fields(br.Text())
+		if len(fields) < minARPFields {
+			return nil, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
+		}
+		flags, err := strconv.ParseUint(fields[fieldARPFlags], 0, 32)
+		if err != nil {
+			return nil, err
+		}
+		if flags&atfCom == 0 {
+			continue
+		}
+		ip := net.ParseIP(fields[fieldARPIP])
+		if ip == nil {
+			return nil, fmt.Errorf("netstat: bad formatted address: %v", fields[fieldARPIP])
+		}
+		mac, err := net.ParseMAC(fields[fieldARPHW])
+		if err != nil {
+			return nil, err
+		}
+		m[ip.String()] = mac
+	}
+	return m, br.Err()
+}
+
+// AnnotateARP sets the RemoteMAC of the entries whose remote address is an
+// on-link IPv4 neighbour with a resolved entry in the ARP table. IPv6
+// neighbours are only known through netlink and are thus left alone.
+func AnnotateARP(s []SockTabEntry) error {
+	f, err := os.Open(pathARPTab)
+	if err != nil {
+		return err
+	}
+	arp, err := parseARPTab(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	for i := range s {
+		e := &s[i]
+		if e.RemoteAddr == nil || e.RemoteAddr.IP.To4() == nil {
+			continue
+		}
+		if mac, ok := arp[e.RemoteAddr.IP.To4().String()]; ok {
+			e.RemoteMAC = mac
+		}
+	}
+	return nil
+}
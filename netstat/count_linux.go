@@ -0,0 +1,268 @@
+package netstat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// field returns the nth whitespace separated field of line without
+// allocating, or nil if line has fewer fields.
+func field(line []byte, n int) []byte {
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i == len(line) {
+			return nil
+		}
+		j := i
+		for j < len(line) && line[j] != ' ' && line[j] != '\t' {
+			j++
+		}
+		if n == 0 {
+			return line[i:j]
+		}
+		n--
+		i = j
+	}
+}
+
+// parseHexByte parses a hexadecimal number of at most two digits
+func parseHexByte(b []byte) (uint8, bool) {
+	if len(b) == 0 || len(b) > 2 {
+		return 0, false
+	}
+	var v uint8
+	for _, c := range b {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		v = v<<4 | c
+	}
+	return v, true
+}
+
+// parseDec parses a decimal number of at most 32 bits
+func parseDec(b []byte) (uint32, bool) {
+	if len(b) == 0 || len(b) > 10 {
+		return 0, false
+	}
+	var v uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + uint64(c-'0')
+	}
+	if v > 1<<32-1 {
+		return 0, false
+	}
+	return uint32(v), true
+}
+
+// eachLine calls fn with each line of the proto socket table, title line
+// excluded, and stops at the first error fn returns
+func eachLine(proto Protocol, fn func(line []byte) error) error {
+	path, err := procTabPath(proto)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewScanner(f)
+
+	// Discard title
+	br.Scan()
+
+	for br.Scan() {
+		if err := fn(br.Bytes()); err != nil {
+			return err
+		}
+	}
+	return br.Err()
+}
+
+func lineState(line []byte) (SkState, error) {
+	st := field(line, fieldState)
+	u, ok := parseHexByte(st)
+	if !ok {
+		return Unknown, fmt.Errorf("netstat: bad state field: %s", st)
+	}
+	return knownState(u), nil
+}
+
+// CountAll returns the number of sockets of proto in each state, states
+// which are not known to the package are counted as Unknown. Unlike
+// the *Socks functions it only looks at the state column of the socket
+// table, so neither entries are allocated nor socket owners are resolved.
+func CountAll(proto Protocol) (map[SkState]int, error) {
+	counts := make(map[SkState]int)
+	err := eachLine(proto, func(line []byte) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		st, err := lineState(line)
+		if err != nil {
+			return err
+		}
+		counts[st]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// UIDState is the key of the counts returned by CountByUIDState
+type UIDState struct {
+	UID   uint32
+	State SkState
+}
+
+// CountByUIDState returns the number of sockets of proto for each pair of
+// owning user and state. Like CountAll, it reads the socket table once and
+// only looks at the state and uid columns.
+func CountByUIDState(proto Protocol) (map[UIDState]int, error) {
+	counts := make(map[UIDState]int)
+	err := eachLine(proto, func(line []byte) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		st, err := lineState(line)
+		if err != nil {
+			return err
+		}
+		uid, ok := parseDec(field(line, fieldUID))
+		if !ok {
+			return fmt.Errorf("netstat: bad uid field: %s", field(line, fieldUID))
+		}
+		counts[UIDState{UID: uid, State: st}]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// eachInode calls fn with the inode of each socket of the TCP and UDP
+// tables, IPv4 and IPv6, and the table it was found in. Sockets without an
+// inode, such as TIME_WAIT ones, are skipped.
+func eachInode(fn func(proto Protocol, ino uint64)) error {
+	for _, proto := range inetProtos {
+		err := eachLine(proto, func(line []byte) error {
+			f := field(line, fieldInode)
+			if f == nil {
+				return nil
+			}
+			ino, err := strconv.ParseUint(string(f), 10, 64)
+			if err != nil {
+				return err
+			}
+			if ino != 0 {
+				fn(proto, ino)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DuplicateInodes reads the TCP and UDP tables, IPv4 and IPv6, and returns
+// the inodes found on more than one line along with the tables they were
+// found in. A socket belongs to exactly one table, so any finding points at
+// a misread table layout or a kernel anomaly. Sockets without an inode,
+// such as TIME_WAIT ones, are not considered.
+func DuplicateInodes() (map[uint64][]Protocol, error) {
+	seen := make(map[uint64][]Protocol)
+	err := eachInode(func(proto Protocol, ino uint64) {
+		seen[ino] = append(seen[ino], proto)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ino, protos := range seen {
+		if len(protos) < 2 {
+			delete(seen, ino)
+		}
+	}
+	return seen, nil
+}
+
+const pathPortRange = "/proc/sys/net/ipv4/ip_local_port_range"
+
+// PortPressure describes how much of the ephemeral port range is held by
+// sockets in TIME_WAIT state
+type PortPressure struct {
+	TimeWait    int    // TIME_WAIT sockets, IPv4 and IPv6
+	First, Last uint16 // ip_local_port_range
+	Utilization float64
+	Warning     bool
+}
+
+func readPortRange() (first, last uint16, err error) {
+	b, err := ioutil.ReadFile(pathPortRange)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("netstat: bad formatted port range: %q", b)
+	}
+	v, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	u, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if u < v {
+		return 0, 0, fmt.Errorf("netstat: bad port range: %d-%d", v, u)
+	}
+	return uint16(v), uint16(u), nil
+}
+
+// TimeWaitPressure compares the number of TCP sockets in TIME_WAIT state
+// against the size of the ephemeral port range. Utilization is given in
+// percent and Warning is set once it reaches warnPercent. Since a port can
+// be reused towards distinct peers, this is an upper bound of the pressure
+// on outbound connections rather than an exact measure.
+func TimeWaitPressure(warnPercent float64) (*PortPressure, error) {
+	first, last, err := readPortRange()
+	if err != nil {
+		return nil, err
+	}
+	p := &PortPressure{First: first, Last: last}
+	for _, proto := range []Protocol{TCP, TCP6} {
+		counts, err := CountAll(proto)
+		if err != nil {
+			return nil, err
+		}
+		p.TimeWait += counts[TimeWait]
+	}
+	p.Utilization = float64(p.TimeWait) * 100 / float64(int(last)-int(first)+1)
+	p.Warning = p.Utilization >= warnPercent
+	return p, nil
+}
@@ -0,0 +1,122 @@
+package netstat
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+func addrKey(a *SockAddr) string {
+	if a == nil {
+		return ""
+	}
+	n := SockAddr{IP: normIP(a.IP), Port: a.Port}
+	return n.String()
+}
+
+// connKey identifies an entry across scans
+func connKey(e *SockTabEntry) string {
+	return e.Host + " " + e.Protocol.String() + " " + addrKey(e.LocalAddr) + "-" + addrKey(e.RemoteAddr)
+}
+
+// ID returns a stable identifier of e, computed over its protocol, local
+// and remote addresses, inode and source host. IPv4-mapped IPv6 addresses
+// are hashed in their IPv4 form. It is suitable as a map key or an event
+// ID across scans since the state is not part of it; note though that the
+// kernel drops the inode of connections entering TIME_WAIT.
+func (e SockTabEntry) ID() string {
+	sum := sha1.Sum([]byte(connKey(&e) + " " + strconv.FormatUint(e.Inode, 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// SameConnection reports whether a and b describe the same connection,
+// possibly seen in different scans: they come from the same host, use the
+// same transport and have the same local and remote addresses. IPv4-mapped
+// IPv6 addresses compare equal to their IPv4 form, so an IPv4 connection
+// read from the tcp table matches its dual stack view from the tcp6 one.
+// The state, the queues and the inode are not compared.
+func SameConnection(a, b SockTabEntry) bool {
+	return a.Host == b.Host &&
+		transport(a.Protocol) == transport(b.Protocol) &&
+		addrKey(a.LocalAddr) == addrKey(b.LocalAddr) &&
+		addrKey(a.RemoteAddr) == addrKey(b.RemoteAddr)
+}
+
+// Diff compares two scans of socket tables and returns the entries
+// of cur missing from old and those of old missing from cur. Entries are
+// matched by their protocol and their local and remote addresses, so a
+// connection whose state changed in between is reported neither as added
+// nor as removed.
+func Diff(old, cur []SockTabEntry) (added, removed []SockTabEntry) {
+	return missing(cur, old), missing(old, cur)
+}
+
+// missing returns the entries of a which are not in b
+func missing(a, b []SockTabEntry) []SockTabEntry {
+	n := make(map[string]int, len(b))
+	for i := range b {
+		n[connKey(&b[i])]++
+	}
+	var s []SockTabEntry
+	for i := range a {
+		k := connKey(&a[i])
+		if n[k] > 0 {
+			n[k]--
+			continue
+		}
+		s = append(s, a[i])
+	}
+	return s
+}
+
+// ChurnRate returns the rates, per second, at which sockets were opened and
+// closed between two scans taken dt apart. Unlike Diff, sockets are told
+// apart by their inode, so that a connection replaced by a new one on the
+// same address pair still counts as churn; entries without an inode, such
+// as TIME_WAIT ones, are ignored.
+func ChurnRate(old, cur []SockTabEntry, dt time.Duration) (opened, closed float64) {
+	if dt <= 0 {
+		return 0, 0
+	}
+	inodes := func(s []SockTabEntry) map[uint64]bool {
+		m := make(map[uint64]bool, len(s))
+		for _, e := range s {
+			if e.Inode != 0 {
+				m[e.Inode] = true
+			}
+		}
+		return m
+	}
+	o, c := inodes(old), inodes(cur)
+	var nopen, nclose int
+	for ino := range c {
+		if !o[ino] {
+			nopen++
+		}
+	}
+	for ino := range o {
+		if !c[ino] {
+			nclose++
+		}
+	}
+	sec := dt.Seconds()
+	return float64(nopen) / sec, float64(nclose) / sec
+}
+
+// WriteSnapshot writes s to w as JSON, suitable to be read back with
+// ReadSnapshot
+func WriteSnapshot(w io.Writer, s []SockTabEntry) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadSnapshot reads the JSON encoded entries written by WriteSnapshot
+func ReadSnapshot(r io.Reader) ([]SockTabEntry, error) {
+	var s []SockTabEntry
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
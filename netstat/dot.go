@@ -0,0 +1,71 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// dotQuote returns s as a DOT quoted string. Only double quotes and
+// backslashes are escaped, DOT has no escapes for other characters;
+// control characters, which process names may contain, are replaced with
+// spaces.
+func dotQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// WriteDOT writes s to w as a Graphviz graph in the DOT language, with a
+// node for each local process, one for each remote host and an edge for
+// each connection, labeled with the protocol, the local and remote ports
+// and the state. Sockets whose owner is unknown share a single "unknown"
+// node. Entries without a peer, such as listeners, are left out. The output
+// can be rendered with e.g. dot -Tsvg.
+func WriteDOT(s []SockTabEntry, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph netstat {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+
+	procs := make(map[int]bool)
+	hosts := make(map[string]string)
+	for _, e := range s {
+		if e.RemoteAddr == nil || e.RemoteAddr.IP.IsUnspecified() {
+			continue
+		}
+		pid, label := 0, "unknown"
+		if e.Process != nil {
+			pid, label = e.Process.Pid, e.Process.String()
+		}
+		from := fmt.Sprintf("p%d", pid)
+		if !procs[pid] {
+			procs[pid] = true
+			fmt.Fprintf(bw, "\t%s [shape=box, label=%s];\n", from, dotQuote(label))
+		}
+		ip := normIP(e.RemoteAddr.IP).String()
+		to, ok := hosts[ip]
+		if !ok {
+			to = fmt.Sprintf("h%d", len(hosts))
+			hosts[ip] = to
+			fmt.Fprintf(bw, "\t%s [shape=ellipse, label=%s];\n", to, dotQuote(ip))
+		}
+		edge := fmt.Sprintf("%v %d -> %d %v", transport(e.Protocol), e.LocalAddr.Port, e.RemoteAddr.Port, e.State)
+		fmt.Fprintf(bw, "\t%s -> %s [label=%s];\n", from, to, dotQuote(edge))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
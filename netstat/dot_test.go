@@ -0,0 +1,49 @@
+package netstat
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDotQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1234/nginx", `"1234/nginx"`},
+		{`a "b" c`, `"a \"b\" c"`},
+		{`back\slash`, `"back\\slash"`},
+		{"tab\tnul\x00del\x7f", `"tab nul del "`},
+		{"caf\u00e9\u00a0", "\"caf\u00e9\u00a0\""},
+	}
+	for _, tt := range tests {
+		if got := dotQuote(tt.in); got != tt.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	s := []SockTabEntry{{
+		Protocol:   TCP,
+		State:      Established,
+		LocalAddr:  &SockAddr{IP: net.IPv4(10, 0, 0, 5), Port: 40000},
+		RemoteAddr: &SockAddr{IP: net.IPv4(1, 2, 3, 4), Port: 443},
+		Process:    &Process{Pid: 42, Name: "odd\x01\"name"},
+	}}
+	var b bytes.Buffer
+	if err := WriteDOT(s, &b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`p42 [shape=box, label="42/odd \"name"];`,
+		`h0 [shape=ellipse, label="1.2.3.4"];`,
+		`p42 -> h0 [label="tcp 40000 -> 443 ESTABLISHED"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package netstat
+
+import "sort"
+
+// BindScope tells from where a bound socket can be reached
+type BindScope uint8
+
+// Bind scopes, by increasing exposure
+const (
+	ScopeLoopback BindScope = iota // loopback address, local host only
+	ScopeExternal                  // specific non loopback address
+	ScopeWildcard                  // 0.0.0.0 or ::, all interfaces
+)
+
+var scopeNames = [...]string{
+	"loopback",
+	"external",
+	"wildcard",
+}
+
+func (b BindScope) String() string {
+	if int(b) >= len(scopeNames) {
+		return "unknown"
+	}
+	return scopeNames[b]
+}
+
+// scopeOf returns the bind scope of the local address of e
+func scopeOf(e *SockTabEntry) BindScope {
+	switch ip := e.LocalAddr.IP; {
+	case ip.IsUnspecified():
+		return ScopeWildcard
+	case ip.IsLoopback():
+		return ScopeLoopback
+	}
+	return ScopeExternal
+}
+
+// Exposure is a TCP listener or an unconnected UDP socket along with the
+// scope of its bind
+type Exposure struct {
+	SockTabEntry
+	Scope BindScope
+}
+
+// listeners returns the entries of s waiting for incoming traffic, see
+// isBound
+func listeners(s []SockTabEntry) []SockTabEntry {
+	var l []SockTabEntry
+	for _, e := range s {
+		if isBound(&e) {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// exposures classifies s and sorts it by decreasing scope, then ascending
+// port, so that the most exposed sockets come first
+func exposures(s []SockTabEntry) []Exposure {
+	x := make([]Exposure, len(s))
+	for i := range s {
+		x[i] = Exposure{SockTabEntry: s[i], Scope: scopeOf(&s[i])}
+	}
+	sort.SliceStable(x, func(i, j int) bool {
+		if x[i].Scope != x[j].Scope {
+			return x[i].Scope > x[j].Scope
+		}
+		return x[i].LocalAddr.Port < x[j].LocalAddr.Port
+	})
+	return x
+}
+
+// DiffListeners compares the TCP listeners and unconnected UDP sockets of
+// two scans, as Diff does, and returns those which appeared in cur and
+// those which went away since old, classified by bind scope. Wildcard
+// binds come first, then the external ones, as a socket starting to listen
+// on them is what exposure monitoring is after. Other entries of the scans
+// are ignored. The owning processes are those of the scans, so cur should
+// be resolved to tell who opened a new listener.
+func DiffListeners(old, cur []SockTabEntry) (added, removed []Exposure) {
+	a, r := Diff(listeners(old), listeners(cur))
+	return exposures(a), exposures(r)
+}
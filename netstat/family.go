@@ -0,0 +1,55 @@
+package netstat
+
+// ProtoFamily is the transport protocol of a socket along with the address
+// family it actually uses. Unlike Protocol it tells IPv4 traffic carried by
+// dual stack IPv6 sockets apart, and it can be used directly as a map key
+// to aggregate entries across tables.
+type ProtoFamily uint8
+
+// Protocol and address family pairs
+const (
+	ProtoUnknown ProtoFamily = iota
+	ProtoTCP4
+	ProtoTCP6
+	ProtoUDP4
+	ProtoUDP6
+)
+
+var familyNames = [...]string{
+	"unknown",
+	"tcp4",
+	"tcp6",
+	"udp4",
+	"udp6",
+}
+
+func (f ProtoFamily) String() string {
+	if int(f) >= len(familyNames) {
+		return familyNames[ProtoUnknown]
+	}
+	return familyNames[f]
+}
+
+// ProtoFamily returns the protocol and address family of e. Entries of the
+// tcp6 and udp6 tables bound to an IPv4-mapped address are reported as
+// tcp4 and udp4 respectively. Wildcard IPv6 binds are reported as IPv6
+// even though they may accept IPv4 traffic as well.
+func (e SockTabEntry) ProtoFamily() ProtoFamily {
+	v4 := e.Protocol == TCP || e.Protocol == UDP
+	if e.LocalAddr != nil && e.LocalAddr.Is4Mapped() {
+		v4 = true
+	}
+	switch transport(e.Protocol) {
+	case TCP:
+		if v4 {
+			return ProtoTCP4
+		}
+		return ProtoTCP6
+	case UDP:
+		if v4 {
+			return ProtoUDP4
+		}
+		return ProtoUDP6
+	}
+	return ProtoUnknown
+}
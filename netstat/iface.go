@@ -0,0 +1,144 @@
+package netstat
+
+import (
+	"net"
+)
+
+// interfaceIPs returns the IP addresses currently assigned to the network
+// interfaces of the host
+func interfaceIPs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		switch v := a.(type) {
+		case *net.IPNet:
+			ips = append(ips, v.IP)
+		case *net.IPAddr:
+			ips = append(ips, v.IP)
+		}
+	}
+	return ips, nil
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, v := range ips {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// StaleBinds returns the TCP listeners and unconnected UDP sockets of s
+// whose local IP address is no longer assigned to any network interface of
+// the host. Such sockets, usually left over after a network
+// reconfiguration, can not receive any traffic. Wildcard and loopback binds
+// are never reported, nor are connected sockets.
+func StaleBinds(s []SockTabEntry) ([]SockTabEntry, error) {
+	ips, err := interfaceIPs()
+	if err != nil {
+		return nil, err
+	}
+	var stale []SockTabEntry
+	for _, e := range s {
+		if !isBound(&e) {
+			continue
+		}
+		ip := e.LocalAddr.IP
+		if ip.IsUnspecified() || ip.IsLoopback() {
+			continue
+		}
+		if !containsIP(ips, ip) {
+			stale = append(stale, e)
+		}
+	}
+	return stale, nil
+}
+
+// ifaceNet is a network assigned to a network interface
+type ifaceNet struct {
+	name string
+	n    *net.IPNet
+}
+
+// interfaceNets returns the networks assigned to the network interfaces of
+// the host
+func interfaceNets() ([]ifaceNet, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var nets []ifaceNet
+	for _, ifi := range ifaces {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if v, ok := a.(*net.IPNet); ok {
+				nets = append(nets, ifaceNet{name: ifi.Name, n: v})
+			}
+		}
+	}
+	return nets, nil
+}
+
+// ifaceOf returns the name of the interface of nets ip is assigned to. A
+// loopback address bound without being assigned, such as 127.0.0.53, is
+// attributed to the interface of the loopback network containing it, as the
+// kernel routes the whole of 127.0.0.0/8 there.
+func ifaceOf(nets []ifaceNet, ip net.IP) string {
+	for _, v := range nets {
+		if v.n.IP.Equal(ip) {
+			return v.name
+		}
+	}
+	if !ip.IsLoopback() {
+		return ""
+	}
+	for _, v := range nets {
+		if v.n.IP.IsLoopback() && v.n.Contains(ip) {
+			return v.name
+		}
+	}
+	return ""
+}
+
+// Interface returns the name of the network interface the IP address of s
+// is assigned to, or routed to for loopback addresses, see ifaceOf. An
+// empty string is returned if there is none, as for wildcard addresses or
+// addresses since removed from the host.
+func (s *SockAddr) Interface() (string, error) {
+	nets, err := interfaceNets()
+	if err != nil {
+		return "", err
+	}
+	return ifaceOf(nets, s.IP), nil
+}
+
+// ListenersByInterface groups the TCP listeners and the unconnected UDP
+// sockets of s by the network interface their local address belongs to,
+// see SockAddr.Interface. Wildcard binds, which accept traffic from all
+// interfaces, are grouped under "all" and binds to an address no interface
+// has anymore under an empty name.
+func ListenersByInterface(s []SockTabEntry) (map[string][]SockTabEntry, error) {
+	nets, err := interfaceNets()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string][]SockTabEntry)
+	for _, e := range s {
+		if !isBound(&e) {
+			continue
+		}
+		name := "all"
+		if !e.LocalAddr.IP.IsUnspecified() {
+			name = ifaceOf(nets, e.LocalAddr.IP)
+		}
+		m[name] = append(m[name], e)
+	}
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package netstat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIfaceOf(t *testing.T) {
+	cidr := func(s string) *net.IPNet {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		return n
+	}
+	nets := []ifaceNet{
+		{"lo", cidr("127.0.0.1/8")},
+		{"lo", cidr("::1/128")},
+		{"eth0", cidr("10.0.0.5/24")},
+	}
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"127.0.0.1", "lo"},
+		{"127.0.0.53", "lo"},
+		{"127.0.1.1", "lo"},
+		{"::ffff:127.0.0.53", "lo"},
+		{"::1", "lo"},
+		{"10.0.0.5", "eth0"},
+		{"::ffff:10.0.0.5", "eth0"},
+		{"10.0.0.7", ""}, // on the subnet, not assigned
+		{"192.168.1.1", ""},
+	}
+	for _, tt := range tests {
+		if got := ifaceOf(nets, net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("%s: got interface %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestListenersByInterfaceLoopback(t *testing.T) {
+	nets, err := interfaceNets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lo := ifaceOf(nets, net.IPv4(127, 0, 0, 1))
+	if lo == "" {
+		t.Skip("no loopback interface")
+	}
+	resolved := SockTabEntry{
+		Protocol:   UDP,
+		State:      Close,
+		LocalAddr:  &SockAddr{IP: net.IPv4(127, 0, 0, 53), Port: 53},
+		RemoteAddr: &SockAddr{IP: net.IPv4zero},
+	}
+	if name, err := resolved.LocalAddr.Interface(); err != nil || name != lo {
+		t.Errorf("Interface() = %q, %v, want %q", name, err, lo)
+	}
+	m, err := ListenersByInterface([]SockTabEntry{resolved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m[lo]) != 1 {
+		t.Errorf("127.0.0.53:53 not grouped under %s: %v", lo, m)
+	}
+}
+
+func TestStaleBinds(t *testing.T) {
+	// TEST-NET-3, not assigned to any interface
+	gone := net.IPv4(203, 0, 113, 7)
+	s := []SockTabEntry{
+		{Protocol: TCP, State: Listen, LocalAddr: &SockAddr{IP: gone, Port: 80}, RemoteAddr: &SockAddr{IP: net.IPv4zero}},
+		{Protocol: UDP, State: Close, LocalAddr: &SockAddr{IP: gone, Port: 53}, RemoteAddr: &SockAddr{IP: net.IPv4zero}},
+		{Protocol: TCP, State: Established, LocalAddr: &SockAddr{IP: gone, Port: 80}, RemoteAddr: &SockAddr{IP: net.IPv4(1, 2, 3, 4), Port: 40000}},
+		{Protocol: TCP, State: TimeWait, LocalAddr: &SockAddr{IP: gone, Port: 80}, RemoteAddr: &SockAddr{IP: net.IPv4(1, 2, 3, 4), Port: 40001}},
+		{Protocol: TCP, State: Listen, LocalAddr: &SockAddr{IP: net.IPv4zero, Port: 22}, RemoteAddr: &SockAddr{IP: net.IPv4zero}},
+		{Protocol: TCP, State: Listen},
+	}
+	stale, err := StaleBinds(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 || stale[0].State != Listen || stale[1].Protocol != UDP {
+		t.Errorf("got stale binds %v, want the TCP listener and the UDP socket", stale)
+	}
+}
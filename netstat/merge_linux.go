@@ -0,0 +1,35 @@
+package netstat
+
+import (
+	"fmt"
+	"io"
+)
+
+// HostDump is a socket table dump received from a host, as parsed by
+// MergeHosts
+type HostDump struct {
+	Host  string
+	Proto Protocol
+	Table io.Reader
+	Procs ProcessTable // optional, see ParseSocktab
+}
+
+// MergeHosts parses the dumps with ParseSocktab and returns the entries of
+// all of them satisfying the accept function in a single slice, in the
+// order of dumps. Each entry carries the name of its source host in Host,
+// which accept can already make use of.
+func MergeHosts(dumps []HostDump, accept AcceptFn) ([]SockTabEntry, error) {
+	var all []SockTabEntry
+	for _, d := range dumps {
+		host := d.Host
+		tabs, err := ParseSocktab(d.Table, d.Proto, func(e *SockTabEntry) bool {
+			e.Host = host
+			return accept(e)
+		}, d.Procs)
+		if err != nil {
+			return nil, fmt.Errorf("netstat: %s: %v", d.Host, err)
+		}
+		all = append(all, tabs...)
+	}
+	return all, nil
+}
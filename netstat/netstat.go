@@ -3,6 +3,7 @@ package netstat
 import (
 	"fmt"
 	"net"
+	"time"
 )
 
 // SockAddr represents an ip:port pair
@@ -15,33 +16,123 @@ func (s *SockAddr) String() string {
 	return fmt.Sprintf("%v:%d", s.IP, s.Port)
 }
 
+// Is4Mapped reports whether the address is an IPv4-mapped IPv6 address
+// (::ffff:0:0/96), as found in the tcp6 and udp6 tables for IPv4 peers of
+// dual stack sockets. Addresses read from the IPv4 tables are 4 bytes long
+// and thus never mapped.
+func (s *SockAddr) Is4Mapped() bool {
+	return len(s.IP) == net.IPv6len && s.IP.To4() != nil
+}
+
 // SockTabEntry type represents each line of the /proc/net/[tcp|udp]
 type SockTabEntry struct {
-	ino        string
+	Inode      uint64
+	Protocol   Protocol
 	LocalAddr  *SockAddr
 	RemoteAddr *SockAddr
 	State      SkState
+	TxQueue    uint32
+	RxQueue    uint32
 	UID        uint32
+	RTO        time.Duration // TCP retransmission timeout, if known
+	ATO        time.Duration // TCP delayed ack timeout, if known
 	Process    *Process
+	Host       string           `json:",omitempty"` // source host of merged tables
+	Cert       *CertSummary     `json:",omitempty"` // set by ProbeTLS
+	RemoteMAC  net.HardwareAddr `json:",omitempty"` // set by AnnotateARP
+
+	// Addresses as found in the socket table, only kept on request
+	LocalAddrRaw  string `json:",omitempty"`
+	RemoteAddrRaw string `json:",omitempty"`
+}
+
+func (e SockTabEntry) String() string {
+	p := ""
+	if e.Process != nil {
+		p = e.Process.String()
+	}
+	return fmt.Sprintf("%-5s %-23s %-23s %-12s %s", e.Protocol, e.LocalAddr, e.RemoteAddr, e.State, p)
 }
 
 // Process holds the PID and process name to which each socket belongs
 type Process struct {
 	Pid  int
 	Name string
+	Unit string // systemd unit, only resolved on request
+
+	start uint64 // start time in clock ticks since boot, 0 if unknown
 }
 
 func (p *Process) String() string {
 	return fmt.Sprintf("%d/%s", p.Pid, p.Name)
 }
 
+// ProcessTable maps socket inodes to the processes owning them
+type ProcessTable map[uint64]*Process
+
 // SkState type represents socket connection state
 type SkState uint8
 
+// Unknown is the state of sockets whose state could not be recognized
+const Unknown SkState = 0x00
+
 func (s SkState) String() string {
+	if int(s) >= len(skStates) {
+		return skStates[Unknown]
+	}
 	return skStates[s]
 }
 
+// knownState maps states outside the range of skStates to Unknown
+func knownState(u uint8) SkState {
+	if int(u) >= len(skStates) {
+		return Unknown
+	}
+	return SkState(u)
+}
+
+// Protocol identifies a socket table
+type Protocol uint8
+
+// Socket tables
+const (
+	TCP Protocol = iota + 1
+	TCP6
+	UDP
+	UDP6
+)
+
+var protoNames = [...]string{
+	"UNKNOWN",
+	"tcp",
+	"tcp6",
+	"udp",
+	"udp6",
+}
+
+func (p Protocol) String() string {
+	if int(p) >= len(protoNames) {
+		return protoNames[0]
+	}
+	return protoNames[p]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+func (p Protocol) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+func (p *Protocol) UnmarshalText(text []byte) error {
+	for i, name := range protoNames {
+		if i > 0 && name == string(text) {
+			*p = Protocol(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("netstat: unknown protocol: %q", text)
+}
+
 // AcceptFn is used to filter socket entries. The value returned indicates
 // whether the element is to be appended to the socket list.
 type AcceptFn func(*SockTabEntry) bool
@@ -1,5 +1,11 @@
 // Package netstat provides primitives for getting socket information on a
 // Linux based operating system.
+//
+// Socket information is read from the /proc/net tables, which lack a few
+// attributes only available through netlink (sock_diag); notably the
+// interface a socket is pinned to with SO_BINDTODEVICE and the round trip
+// time estimates of TCP connections are not reported. The retransmission
+// and delayed ack timeouts, which derive from the latter, are.
 package netstat
 
 import (
@@ -15,6 +21,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -27,6 +34,34 @@ const (
 	ipv6StrLen = 32
 )
 
+// Column indexes of the socket tables. Although the title lines differ,
+// tcp[6] and udp[6] tables share the same layout up to the inode column;
+// tx_queue:rx_queue and tr:tm->when are printed as single fields, so
+// udp lines look like:
+//
+//	sl local_address rem_address st tx:rx tr:tm retrnsmt uid timeout inode ref pointer drops
+const (
+	fieldLocalAddr = 1
+	fieldRemAddr   = 2
+	fieldState     = 3
+	fieldQueues    = 4
+	fieldUID       = 7
+	fieldInode     = 9
+
+	minFields = 12
+
+	// Full TCP sockets, unlike TIME_WAIT and SYN_RECV ones, carry timer
+	// columns past the inode:
+	//
+	//	... inode ref pointer rto ato quick|pingpong cwnd ssthresh
+	fieldRTO     = 12
+	fieldATO     = 13
+	minTCPFields = 14
+
+	// userHZ is the unit of the timer columns, clock ticks per second
+	userHZ = 100
+)
+
 // Socket states
 const (
 	Established SkState = 0x01
@@ -57,9 +92,26 @@ var skStates = [...]string{
 	"CLOSING",
 }
 
+var procTabs = [...]string{
+	TCP:  pathTCPTab,
+	TCP6: pathTCP6Tab,
+	UDP:  pathUDPTab,
+	UDP6: pathUDP6Tab,
+}
+
+func procTabPath(p Protocol) (string, error) {
+	if p == 0 || int(p) >= len(procTabs) {
+		return "", fmt.Errorf("netstat: unknown protocol: %d", p)
+	}
+	return procTabs[p], nil
+}
+
 // Errors returned by gonetstat
 var (
 	ErrNotEnoughFields = errors.New("gonetstat: not enough fields in the line")
+	ErrTruncated       = errors.New("gonetstat: socket table truncated")
+	ErrNoParent        = errors.New("gonetstat: process has no parent")
+	ErrPidReused       = errors.New("gonetstat: process has exited and its pid has been reused")
 )
 
 func parseIPv4(s string) (net.IP, error) {
@@ -114,7 +166,100 @@ func parseAddr(s string) (*SockAddr, error) {
 	return &SockAddr{IP: ip, Port: uint16(v)}, nil
 }
 
-func parseSocktab(r io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
+// parseQueues parses the tx_queue:rx_queue column
+func parseQueues(s string) (tx, rx uint32, err error) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("netstat: bad formatted string: %v", s)
+	}
+	v, err := strconv.ParseUint(s[:i], 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	u, err := strconv.ParseUint(s[i+1:], 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(v), uint32(u), nil
+}
+
+// parseTicks parses a timer column given in clock ticks
+func parseTicks(s string) (time.Duration, error) {
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(v) * time.Second / userHZ, nil
+}
+
+// stateFilter reports whether the lines of a socket table in state st are
+// to be parsed at all. Unlike AcceptFn, it is consulted before a line is
+// split into fields.
+type stateFilter func(st SkState) bool
+
+// parseFields parses the fields of a socket table line into an entry
+func parseFields(fields []string, proto Protocol) (SockTabEntry, error) {
+	e := SockTabEntry{Protocol: proto}
+	if len(fields) < minFields {
+		return e, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
+	}
+	addr, err := parseAddr(fields[fieldLocalAddr])
+	if err != nil {
+		return e, err
+	}
+	e.LocalAddr = addr
+	addr, err = parseAddr(fields[fieldRemAddr])
+	if err != nil {
+		return e, err
+	}
+	e.RemoteAddr = addr
+	u, err := strconv.ParseUint(fields[fieldState], 16, 8)
+	if err != nil {
+		return e, err
+	}
+	e.State = knownState(uint8(u))
+	e.TxQueue, e.RxQueue, err = parseQueues(fields[fieldQueues])
+	if err != nil {
+		return e, err
+	}
+	u, err = strconv.ParseUint(fields[fieldUID], 10, 32)
+	if err != nil {
+		return e, err
+	}
+	e.UID = uint32(u)
+	e.Inode, err = strconv.ParseUint(fields[fieldInode], 10, 64)
+	if err != nil {
+		return e, err
+	}
+	if (proto == TCP || proto == TCP6) && len(fields) >= minTCPFields {
+		e.RTO, err = parseTicks(fields[fieldRTO])
+		if err != nil {
+			return e, err
+		}
+		e.ATO, err = parseTicks(fields[fieldATO])
+		if err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// checkFamily returns an error if the addresses of e are not of the length
+// expected for the table proto
+func checkFamily(e *SockTabEntry, proto Protocol) error {
+	want := net.IPv4len
+	if proto == TCP6 || proto == UDP6 {
+		want = net.IPv6len
+	}
+	for _, a := range []*SockAddr{e.LocalAddr, e.RemoteAddr} {
+		if a != nil && len(a.IP) != want {
+			return fmt.Errorf("netstat: %d-byte address %v in %v table", len(a.IP), a.IP, proto)
+		}
+	}
+	return nil
+}
+
+func parseSocktab(r io.Reader, proto Protocol, opts *scanOpts, accept AcceptFn) ([]SockTabEntry, error) {
 	br := bufio.NewScanner(r)
 	tab := make([]SockTabEntry, 0, 4)
 
@@ -122,38 +267,41 @@ func parseSocktab(r io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
 	br.Scan()
 
 	for br.Scan() {
-		var e SockTabEntry
+		if !opts.wantLine(br.Bytes()) {
+			continue
+		}
 		line := br.Text()
 		// Skip comments
 		if i := strings.Index(line, "#"); i >= 0 {
 			line = line[:i]
 		}
 		fields := strings.Fields(line)
-		if len(fields) < 12 {
-			return nil, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
+		var (
+			e   SockTabEntry
+			err error
+		)
+		if opts.parse != nil {
+			e, err = opts.parse(fields)
+			if e.Protocol == 0 {
+				e.Protocol = proto
+			}
+		} else {
+			e, err = parseFields(fields, proto)
 		}
-		addr, err := parseAddr(fields[1])
-		if err != nil {
-			return nil, err
+		if err == nil && opts.family {
+			err = checkFamily(&e, proto)
 		}
-		e.LocalAddr = addr
-		addr, err = parseAddr(fields[2])
 		if err != nil {
 			return nil, err
 		}
-		e.RemoteAddr = addr
-		u, err := strconv.ParseUint(fields[3], 16, 8)
-		if err != nil {
-			return nil, err
+		if opts.raw && len(fields) > fieldRemAddr {
+			e.LocalAddrRaw = fields[fieldLocalAddr]
+			e.RemoteAddrRaw = fields[fieldRemAddr]
 		}
-		e.State = SkState(u)
-		u, err = strconv.ParseUint(fields[7], 10, 32)
-		if err != nil {
-			return nil, err
-		}
-		e.UID = uint32(u)
-		e.ino = fields[9]
 		if accept(&e) {
+			if opts.max > 0 && len(tab) == opts.max {
+				return tab, ErrTruncated
+			}
 			tab = append(tab, e)
 		}
 	}
@@ -163,8 +311,10 @@ func parseSocktab(r io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
 type procFd struct {
 	base  string
 	pid   int
-	sktab []SockTabEntry
+	want  map[uint64]bool // inodes looked for, only ever read
+	owned []uint64        // inodes of want owned by the process
 	p     *Process
+	opts  *scanOpts
 }
 
 const sockPrefix = "socket:["
@@ -184,14 +334,37 @@ func getProcName(s []byte) string {
 	return string(s[i+1 : j])
 }
 
+// linkInode returns the inode of a socket given its fd link name, which is
+// of the form socket:[5860846]
+func linkInode(lname string) (uint64, bool) {
+	if !strings.HasPrefix(lname, sockPrefix) || !strings.HasSuffix(lname, "]") {
+		return 0, false
+	}
+	ino, err := strconv.ParseUint(lname[len(sockPrefix):len(lname)-1], 10, 64)
+	return ino, err == nil
+}
+
+// readProcess returns the process rooted at base, e.g. /proc/1234
+func readProcess(base string, pid int, opts *scanOpts) (*Process, error) {
+	st, err := readProcStat(base)
+	if err != nil {
+		return nil, err
+	}
+	p := &Process{Pid: pid, Name: st.name, start: st.starttime}
+	if opts.unit {
+		p.Unit = procUnit(base)
+	}
+	return p, nil
+}
+
 func (p *procFd) iterFdDir() {
 	// link name is of the form socket:[5860846]
 	fddir := path.Join(p.base, "/fd")
 	fi, err := ioutil.ReadDir(fddir)
 	if err != nil {
+		p.opts.logf("netstat: skipping pid %d: %v", p.pid, err)
 		return
 	}
-	var buf [128]byte
 
 	for _, file := range fi {
 		fd := path.Join(fddir, file.Name())
@@ -199,39 +372,52 @@ func (p *procFd) iterFdDir() {
 		if err != nil || !strings.HasPrefix(lname, sockPrefix) {
 			continue
 		}
-
-		for i := range p.sktab {
-			sk := &p.sktab[i]
-			ss := sockPrefix + sk.ino + "]"
-			if ss != lname {
-				continue
-			}
-			if p.p == nil {
-				stat, err := os.Open(path.Join(p.base, "stat"))
-				if err != nil {
-					return
-				}
-				n, err := stat.Read(buf[:])
-				stat.Close()
-				if err != nil {
-					return
-				}
-				z := bytes.SplitN(buf[:n], []byte(" "), 3)
-				name := getProcName(z[1])
-				p.p = &Process{p.pid, name}
+		ino, ok := linkInode(lname)
+		if !ok {
+			continue
+		}
+		if !p.want[ino] {
+			continue
+		}
+		if p.p == nil {
+			p.p, err = readProcess(p.base, p.pid, p.opts)
+			if err != nil {
+				p.opts.logf("netstat: skipping pid %d: %v", p.pid, err)
+				return
 			}
-			sk.Process = p.p
 		}
+		p.owned = append(p.owned, ino)
 	}
 }
 
-func extractProcInfo(sktab []SockTabEntry) {
-	const basedir = "/proc"
-	fi, err := ioutil.ReadDir(basedir)
+// sockInodes returns the inodes of the sockets opened by the process rooted
+// at base, e.g. /proc/1234
+func sockInodes(base string) (map[uint64]bool, error) {
+	fddir := path.Join(base, "fd")
+	fi, err := ioutil.ReadDir(fddir)
 	if err != nil {
-		return
+		return nil, err
+	}
+	inodes := make(map[uint64]bool)
+	for _, file := range fi {
+		lname, err := os.Readlink(path.Join(fddir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if ino, ok := linkInode(lname); ok {
+			inodes[ino] = true
+		}
 	}
+	return inodes, nil
+}
 
+// listPids returns the PIDs of the processes visible in /proc
+func listPids() ([]int, error) {
+	fi, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int, 0, len(fi))
 	for _, file := range fi {
 		if !file.IsDir() {
 			continue
@@ -240,47 +426,180 @@ func extractProcInfo(sktab []SockTabEntry) {
 		if err != nil {
 			continue
 		}
-		base := path.Join(basedir, file.Name())
-		proc := procFd{base: base, pid: pid, sktab: sktab}
-		proc.iterFdDir()
+		pids = append(pids, pid)
 	}
+	return pids, nil
 }
 
-// doNetstat - collect information about network port status
-func doNetstat(path string, fn AcceptFn) ([]SockTabEntry, error) {
-	f, err := os.Open(path)
+// resolveInodes walks /proc and stores the owner of each socket inode
+// present in inodes as its value. Inodes not owned by any of the visible
+// processes are left untouched.
+func resolveInodes(inodes map[uint64]*Process, opts *scanOpts) {
+	pids, err := listPids()
 	if err != nil {
-		return nil, err
+		opts.logf("netstat: can not resolve socket owners: %v", err)
+		return
+	}
+	want := make(map[uint64]bool, len(inodes))
+	for ino := range inodes {
+		want[ino] = true
 	}
-	tabs, err := parseSocktab(f, fn)
-	f.Close()
+	if opts.workers > 0 {
+		resolveConcurrent(inodes, want, pids, opts)
+		return
+	}
+	for _, pid := range pids {
+		r := opts.owner(pid, want)
+		for _, ino := range r.inodes {
+			inodes[ino] = r.p
+		}
+	}
+}
+
+func extractProcInfo(sktab []SockTabEntry, opts *scanOpts) {
+	if opts.procs != nil {
+		for i := range sktab {
+			sktab[i].Process = opts.procs[sktab[i].Inode]
+		}
+		return
+	}
+	inodes := make(map[uint64]*Process, len(sktab))
+	for i := range sktab {
+		inodes[sktab[i].Inode] = nil
+	}
+	resolveInodes(inodes, opts)
+	for i := range sktab {
+		sktab[i].Process = inodes[sktab[i].Inode]
+	}
+}
+
+// ParseSocktab parses a socket table in the format of /proc/net/[tcp|udp]
+// read from r, such as a dump taken on another host, and returns the
+// entries satisfying the accept function. proto tells which table the dump
+// was taken from. If procs is not nil the entries are attributed to their
+// owners by looking up their inodes in it, otherwise Process is left nil;
+// the local /proc is never consulted.
+func ParseSocktab(r io.Reader, proto Protocol, accept AcceptFn, procs ProcessTable) ([]SockTabEntry, error) {
+	tabs, err := parseSocktab(r, proto, &scanOpts{}, accept)
 	if err != nil {
 		return nil, err
 	}
-	extractProcInfo(tabs)
+	if procs != nil {
+		extractProcInfo(tabs, &scanOpts{procs: procs})
+	}
 	return tabs, nil
 }
 
+func readSocktab(proto Protocol, opts *scanOpts, fn AcceptFn) ([]SockTabEntry, error) {
+	path, err := procTabPath(proto)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSocktab(f, proto, opts, fn)
+}
+
+// doNetstat - collect information about network port status
+func doNetstat(proto Protocol, opts *scanOpts, fn AcceptFn) ([]SockTabEntry, error) {
+	tabs, err := readSocktab(proto, opts, fn)
+	if err != nil && err != ErrTruncated {
+		return nil, err
+	}
+	if !opts.noProc {
+		extractProcInfo(tabs, opts)
+	}
+	return tabs, err
+}
+
 // TCPSocks returns a slice of active TCP sockets containing only those
 // elements that satisfy the accept function
 func osTCPSocks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathTCPTab, accept)
+	return doNetstat(TCP, &scanOpts{}, accept)
 }
 
 // TCP6Socks returns a slice of active TCP IPv4 sockets containing only those
 // elements that satisfy the accept function
 func osTCP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathTCP6Tab, accept)
+	return doNetstat(TCP6, &scanOpts{}, accept)
 }
 
 // UDPSocks returns a slice of active UDP sockets containing only those
 // elements that satisfy the accept function
 func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathUDPTab, accept)
+	return doNetstat(UDP, &scanOpts{}, accept)
 }
 
 // UDP6Socks returns a slice of active UDP IPv6 sockets containing only those
 // elements that satisfy the accept function
 func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathUDP6Tab, accept)
+	return doNetstat(UDP6, &scanOpts{}, accept)
+}
+
+func isListen(st SkState) bool { return st == Listen }
+
+// TCPListeners returns the listening TCP sockets of both the IPv4 and the
+// IPv6 tables. Lines whose state is not LISTEN are skipped before being
+// parsed, which makes it considerably cheaper than filtering the result of
+// TCPSocks on busy hosts.
+func TCPListeners() ([]SockTabEntry, error) {
+	opts := &scanOpts{want: isListen}
+	tabs, err := readSocktab(TCP, opts, NoopFilter)
+	if err != nil {
+		return nil, err
+	}
+	tabs6, err := readSocktab(TCP6, opts, NoopFilter)
+	if err != nil {
+		return nil, err
+	}
+	tabs = append(tabs, tabs6...)
+	extractProcInfo(tabs, opts)
+	return tabs, nil
+}
+
+// TCPSocksMap returns the active TCP sockets keyed by their inode. Sockets
+// without an inode, such as those in TIME_WAIT state, are left out since
+// they all share the inode 0.
+func TCPSocksMap() (map[uint64]SockTabEntry, error) {
+	tabs, err := doNetstat(TCP, &scanOpts{}, func(e *SockTabEntry) bool {
+		return e.Inode != 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint64]SockTabEntry, len(tabs))
+	for _, e := range tabs {
+		m[e.Inode] = e
+	}
+	return m, nil
+}
+
+// SocktabHeader returns the title line of the proto socket table, which
+// parsing otherwise discards, e.g.
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//
+// It is meant for detecting changes in the table layout across kernel
+// versions. Surrounding white space is trimmed.
+func SocktabHeader(proto Protocol) (string, error) {
+	path, err := procTabPath(proto)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	br := bufio.NewScanner(f)
+	if !br.Scan() {
+		if err := br.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	return strings.TrimSpace(br.Text()), nil
 }
@@ -0,0 +1,89 @@
+package netstat
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+// Captured tables, with the inodes changed to stand apart from the
+// surrounding columns. udp lines have 13 fields, the last one being drops;
+// full tcp lines have 17, carrying timers past the inode.
+const (
+	udpTab = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  206: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 20127 2 0000000000000000 0
+  702: 3500007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 18625 2 0000000000000000 3
+`
+	udp6Tab = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  137: 00000000000000000000000001000000:0222 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 21450 2 0000000000000000 0
+`
+	tcpTab = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   101        0 20667 1 0000000000000000 100 0 0 10 0
+`
+)
+
+func TestParseSocktabInodes(t *testing.T) {
+	if fieldInode != 9 {
+		t.Fatalf("fieldInode = %d, want 9", fieldInode)
+	}
+	dhcp := &Process{Pid: 612, Name: "dhclient"}
+	resolved := &Process{Pid: 480, Name: "systemd-resolve"}
+	dhcp6 := &Process{Pid: 701, Name: "dhclient"}
+	mysql := &Process{Pid: 903, Name: "mysqld"}
+	procs := ProcessTable{
+		20127: dhcp,
+		18625: resolved,
+		21450: dhcp6,
+		20667: mysql,
+	}
+	tests := []struct {
+		proto  Protocol
+		tab    string
+		fields int
+		want   []SockTabEntry
+	}{
+		{UDP, udpTab, 13, []SockTabEntry{
+			{Inode: 20127, UID: 0, LocalAddr: &SockAddr{IP: net.IPv4(0, 0, 0, 0), Port: 68}, Process: dhcp},
+			{Inode: 18625, UID: 101, LocalAddr: &SockAddr{IP: net.IPv4(127, 0, 0, 53), Port: 53}, Process: resolved},
+		}},
+		{UDP6, udp6Tab, 13, []SockTabEntry{
+			{Inode: 21450, UID: 0, LocalAddr: &SockAddr{IP: net.IPv6loopback, Port: 546}, Process: dhcp6},
+		}},
+		{TCP, tcpTab, 17, []SockTabEntry{
+			{Inode: 20667, UID: 101, LocalAddr: &SockAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3306}, Process: mysql},
+		}},
+	}
+	for _, tt := range tests {
+		lines := strings.Split(strings.TrimSpace(tt.tab), "\n")[1:]
+		for _, l := range lines {
+			if n := len(strings.Fields(l)); n != tt.fields {
+				t.Fatalf("%v fixture: got %d fields, want %d: %q", tt.proto, n, tt.fields, l)
+			}
+		}
+		got, err := ParseSocktab(strings.NewReader(tt.tab), tt.proto, NoopFilter, procs)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.proto, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%v: got %d entries, want %d", tt.proto, len(got), len(tt.want))
+		}
+		for i, w := range tt.want {
+			g := got[i]
+			if g.Protocol != tt.proto {
+				t.Errorf("%v entry %d: protocol %v", tt.proto, i, g.Protocol)
+			}
+			if g.Inode != w.Inode {
+				t.Errorf("%v entry %d: inode %d, want %d", tt.proto, i, g.Inode, w.Inode)
+			}
+			if g.UID != w.UID {
+				t.Errorf("%v entry %d: uid %d, want %d", tt.proto, i, g.UID, w.UID)
+			}
+			if !g.LocalAddr.IP.Equal(w.LocalAddr.IP) || g.LocalAddr.Port != w.LocalAddr.Port {
+				t.Errorf("%v entry %d: local address %v, want %v", tt.proto, i, g.LocalAddr, w.LocalAddr)
+			}
+			if g.Process != w.Process {
+				t.Errorf("%v entry %d: process %v, want %v", tt.proto, i, g.Process, w.Process)
+			}
+		}
+	}
+}
@@ -471,8 +471,9 @@ type winSockEnt interface {
 	Process(snp ProcessSnapshot) *Process
 }
 
-func toSockTabEntry(ws winSockEnt, snp ProcessSnapshot) SockTabEntry {
+func toSockTabEntry(ws winSockEnt, proto Protocol, snp ProcessSnapshot) SockTabEntry {
 	return SockTabEntry{
+		Protocol:   proto,
 		LocalAddr:  ws.LocalSock(),
 		RemoteAddr: ws.RemoteSock(),
 		State:      ws.SockState(),
@@ -492,7 +493,7 @@ func osTCPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i], snp)
+		ent := toSockTabEntry(&s[i], TCP, snp)
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -513,7 +514,7 @@ func osTCP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i], snp)
+		ent := toSockTabEntry(&s[i], TCP6, snp)
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -534,7 +535,7 @@ func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i], snp)
+		ent := toSockTabEntry(&s[i], UDP, snp)
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -555,7 +556,7 @@ func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i], snp)
+		ent := toSockTabEntry(&s[i], UDP6, snp)
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
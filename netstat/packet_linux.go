@@ -0,0 +1,117 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const pathPacketTab = "/proc/net/packet"
+
+// PacketSockEntry type represents each line of the /proc/net/packet, that
+// is an AF_PACKET socket as opened by tcpdump and other capture tools
+type PacketSockEntry struct {
+	Inode   uint64
+	Type    int    // SOCK_RAW or SOCK_DGRAM
+	Proto   uint16 // ethertype in host byte order, 0x0003 is ETH_P_ALL
+	IfIndex int    // 0 if not bound to an interface
+	Iface   string
+	Running bool
+	Rmem    uint32
+	UID     uint32
+	Process *Process
+}
+
+// columns of the /proc/net/packet:
+//
+//	sk RefCnt Type Proto Iface R Rmem User Inode
+const (
+	fieldPktType    = 2
+	fieldPktProto   = 3
+	fieldPktIface   = 4
+	fieldPktRun     = 5
+	fieldPktRmem    = 6
+	fieldPktUID     = 7
+	fieldPktInode   = 8
+	minPacketFields = 9
+)
+
+func parsePacketTab(r io.Reader) ([]PacketSockEntry, error) {
+	br := bufio.NewScanner(r)
+	var tab []PacketSockEntry
+
+	// Discard title
+	br.Scan()
+
+	for br.Scan() {
+		var e PacketSockEntry
+		fields := strings.Fields(br.Text())
+		if len(fields) < minPacketFields {
+			return nil, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
+		}
+		v, err := strconv.Atoi(fields[fieldPktType])
+		if err != nil {
+			return nil, err
+		}
+		e.Type = v
+		u, err := strconv.ParseUint(fields[fieldPktProto], 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		e.Proto = uint16(u)
+		v, err = strconv.Atoi(fields[fieldPktIface])
+		if err != nil {
+			return nil, err
+		}
+		e.IfIndex = v
+		if v > 0 {
+			if ifi, err := net.InterfaceByIndex(v); err == nil {
+				e.Iface = ifi.Name
+			}
+		}
+		e.Running = fields[fieldPktRun] == "1"
+		u, err = strconv.ParseUint(fields[fieldPktRmem], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		e.Rmem = uint32(u)
+		u, err = strconv.ParseUint(fields[fieldPktUID], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		e.UID = uint32(u)
+		e.Inode, err = strconv.ParseUint(fields[fieldPktInode], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tab = append(tab, e)
+	}
+	return tab, br.Err()
+}
+
+// PacketSocks returns a slice of the AF_PACKET sockets, along with the
+// processes owning them
+func PacketSocks() ([]PacketSockEntry, error) {
+	f, err := os.Open(pathPacketTab)
+	if err != nil {
+		return nil, err
+	}
+	tab, err := parsePacketTab(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[uint64]*Process, len(tab))
+	for i := range tab {
+		inodes[tab[i].Inode] = nil
+	}
+	resolveInodes(inodes, &scanOpts{})
+	for i := range tab {
+		tab[i].Process = inodes[tab[i].Inode]
+	}
+	return tab, nil
+}
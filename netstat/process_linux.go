@@ -0,0 +1,225 @@
+package netstat
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListeningPortsOf returns the TCP ports, both IPv4 and IPv6, the process
+// identified by pid is listening on, in ascending order. Only the file
+// descriptors of that process are inspected rather than the whole of /proc.
+func ListeningPortsOf(pid int) ([]uint16, error) {
+	inodes, err := sockInodes(path.Join("/proc", strconv.Itoa(pid)))
+	if err != nil {
+		return nil, err
+	}
+	owned := func(e *SockTabEntry) bool { return inodes[e.Inode] }
+	opts := &scanOpts{want: isListen}
+	seen := make(map[uint16]bool)
+	var ports []uint16
+	for _, proto := range []Protocol{TCP, TCP6} {
+		tabs, err := readSocktab(proto, opts, owned)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range tabs {
+			if !seen[e.LocalAddr.Port] {
+				seen[e.LocalAddr.Port] = true
+				ports = append(ports, e.LocalAddr.Port)
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports, nil
+}
+
+// ProcessSockets holds all the sockets owned by a single process, grouped
+// by the table they were found in
+type ProcessSockets struct {
+	Process *Process
+	Inet    map[Protocol][]SockTabEntry
+	Unix    []UnixSockEntry
+}
+
+// inetProtos are the socket tables holding TCP and UDP sockets
+var inetProtos = [...]Protocol{TCP, TCP6, UDP, UDP6}
+
+// ownedSocks returns the entries of proto whose inode is in inodes,
+// attributed to p
+func ownedSocks(proto Protocol, inodes map[uint64]bool, p *Process) ([]SockTabEntry, error) {
+	tabs, err := readSocktab(proto, &scanOpts{}, func(e *SockTabEntry) bool {
+		return inodes[e.Inode]
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range tabs {
+		tabs[i].Process = p
+	}
+	return tabs, nil
+}
+
+// SocksOf returns the TCP, UDP and unix domain sockets owned by the process
+// identified by pid. The file descriptors of the process are read only once
+// and each of them is looked up in the socket tables.
+func SocksOf(pid int) (*ProcessSockets, error) {
+	base := path.Join("/proc", strconv.Itoa(pid))
+	inodes, err := sockInodes(base)
+	if err != nil {
+		return nil, err
+	}
+	p, err := readProcess(base, pid, &scanOpts{})
+	if err != nil {
+		return nil, err
+	}
+	ps := &ProcessSockets{
+		Process: p,
+		Inet:    make(map[Protocol][]SockTabEntry),
+	}
+	for _, proto := range inetProtos {
+		tabs, err := ownedSocks(proto, inodes, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(tabs) > 0 {
+			ps.Inet[proto] = tabs
+		}
+	}
+	utab, err := readUnixTab()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range utab {
+		if inodes[e.Inode] {
+			e.Process = p
+			ps.Unix = append(ps.Unix, e)
+		}
+	}
+	return ps, nil
+}
+
+// SelfSocks returns the TCP and UDP sockets, IPv4 and IPv6, owned by the
+// calling process. Only /proc/self is inspected, which makes it cheap
+// enough for periodic self checks such as whether a server is still
+// listening on its port.
+func SelfSocks() ([]SockTabEntry, error) {
+	const base = "/proc/self"
+	inodes, err := sockInodes(base)
+	if err != nil {
+		return nil, err
+	}
+	p, err := readProcess(base, os.Getpid(), &scanOpts{})
+	if err != nil {
+		return nil, err
+	}
+	var all []SockTabEntry
+	for _, proto := range inetProtos {
+		tabs, err := ownedSocks(proto, inodes, p)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tabs...)
+	}
+	return all, nil
+}
+
+// ProcessesWithSockets returns the names of the processes owning at least
+// one TCP or UDP socket, IPv4 or IPv6, along with the number of sockets
+// they own. Processes sharing a name are counted together.
+func ProcessesWithSockets() (map[string]int, error) {
+	opts := &scanOpts{}
+	var all []SockTabEntry
+	for _, proto := range inetProtos {
+		tabs, err := readSocktab(proto, opts, func(e *SockTabEntry) bool {
+			return e.Inode != 0
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tabs...)
+	}
+	extractProcInfo(all, opts)
+	names := make(map[string]int)
+	for _, e := range all {
+		if e.Process != nil {
+			names[e.Process.Name]++
+		}
+	}
+	return names, nil
+}
+
+// procStat holds the fields of /proc/<pid>/stat needed to identify a
+// process and walk up the process tree
+type procStat struct {
+	name      string
+	ppid      int
+	starttime uint64 // clock ticks since boot
+}
+
+// readProcStat reads the stat file of the process rooted at base, e.g.
+// /proc/1234
+func readProcStat(base string) (*procStat, error) {
+	b, err := ioutil.ReadFile(path.Join(base, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// pid (comm) state ppid pgrp ..., comm may contain spaces and
+	// parentheses, the fields following it start after the last ')'
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return nil, fmt.Errorf("netstat: bad formatted stat: %s", b)
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	const (
+		fieldPPid      = 1
+		fieldStartTime = 19
+	)
+	if len(fields) <= fieldStartTime {
+		return nil, fmt.Errorf("netstat: not enough fields in stat: %v", len(fields))
+	}
+	ppid, err := strconv.Atoi(fields[fieldPPid])
+	if err != nil {
+		return nil, err
+	}
+	start, err := strconv.ParseUint(fields[fieldStartTime], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &procStat{name: getProcName(b), ppid: ppid, starttime: start}, nil
+}
+
+// Parent returns the parent of p, e.g. the sshd or service manager a socket
+// owner has been spawned by. ErrNoParent is returned for processes without
+// a parent, such as init, as well as for those whose parent has exited and
+// whose parent PID has since been reused by a younger process. If p itself
+// has exited and its PID has been reused since it was resolved,
+// ErrPidReused is returned rather than the parent of the new process.
+func (p *Process) Parent() (*Process, error) {
+	st, err := readProcStat(path.Join("/proc", strconv.Itoa(p.Pid)))
+	if err != nil {
+		return nil, err
+	}
+	if p.start != 0 && st.starttime != p.start {
+		return nil, ErrPidReused
+	}
+	if st.ppid == 0 {
+		return nil, ErrNoParent
+	}
+	pst, err := readProcStat(path.Join("/proc", strconv.Itoa(st.ppid)))
+	if os.IsNotExist(err) {
+		return nil, ErrNoParent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pst.starttime > st.starttime {
+		return nil, ErrNoParent
+	}
+	return &Process{Pid: st.ppid, Name: pst.name, start: pst.starttime}, nil
+}
@@ -0,0 +1,33 @@
+package netstat
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParent(t *testing.T) {
+	p, err := readProcess("/proc/self", os.Getpid(), &scanOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.start == 0 {
+		t.Fatal("start time not recorded")
+	}
+	pp, err := p.Parent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.Pid != os.Getppid() {
+		t.Errorf("got parent pid %d, want %d", pp.Pid, os.Getppid())
+	}
+	if pp.start == 0 || pp.start > p.start {
+		t.Errorf("parent start time %d, child %d", pp.start, p.start)
+	}
+
+	// Same PID, different start time: a process which has since exited
+	reused := *p
+	reused.start++
+	if _, err := reused.Parent(); err != ErrPidReused {
+		t.Errorf("got error %v, want %v", err, ErrPidReused)
+	}
+}
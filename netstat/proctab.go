@@ -0,0 +1,129 @@
+package netstat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// nextField splits s at the first run of white space
+func nextField(s string) (field, rest string) {
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeft(s[i:], " \t")
+}
+
+// ReadProcessTable reads a process table in the text format written by
+// WriteProcessTable: one socket per line, made up of the inode, the PID
+// and the name of the owning process separated by white space, e.g.
+//
+//	5860846 1234 nginx
+//	5860850 99 my daemon
+//
+// The name extends to the end of the line and may thus contain spaces.
+// Empty lines and lines starting with # are ignored.
+func ReadProcessTable(r io.Reader) (ProcessTable, error) {
+	t := make(ProcessTable)
+	procs := make(map[int]*Process)
+	br := bufio.NewScanner(r)
+	for br.Scan() {
+		line := strings.TrimSpace(br.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f0, rest := nextField(line)
+		f1, name := nextField(rest)
+		if name == "" {
+			return nil, fmt.Errorf("netstat: not enough fields: %v", line)
+		}
+		ino, err := strconv.ParseUint(f0, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(f1)
+		if err != nil {
+			return nil, err
+		}
+		p := procs[pid]
+		if p == nil {
+			p = &Process{Pid: pid, Name: name}
+			procs[pid] = p
+		}
+		t[ino] = p
+	}
+	return t, br.Err()
+}
+
+// WriteProcessTable writes t to w in the format read by ReadProcessTable
+func WriteProcessTable(w io.Writer, t ProcessTable) error {
+	bw := bufio.NewWriter(w)
+	for ino, p := range t {
+		if p == nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(bw, "%d %d %s\n", ino, p.Pid, p.Name); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Apply sets the Process of the entries in s from t. Entries whose inode is
+// not in t are left without an owner.
+func (t ProcessTable) Apply(s []SockTabEntry) {
+	for i := range s {
+		s[i].Process = t[s[i].Inode]
+	}
+}
+
+// procInodes is the JSON form of a process and the sockets it owns
+type procInodes struct {
+	Pid    int
+	Name   string
+	Unit   string `json:",omitempty"`
+	Inodes []uint64
+}
+
+// MarshalJSON encodes t as a list of processes, each with the inodes of
+// the sockets it owns, so that a process shows up once however many
+// sockets it has.
+func (t ProcessTable) MarshalJSON() ([]byte, error) {
+	idx := make(map[*Process]int)
+	list := []procInodes{}
+	for ino, p := range t {
+		if p == nil {
+			continue
+		}
+		i, ok := idx[p]
+		if !ok {
+			i = len(list)
+			idx[p] = i
+			list = append(list, procInodes{Pid: p.Pid, Name: p.Name, Unit: p.Unit})
+		}
+		list[i].Inodes = append(list[i].Inodes, ino)
+	}
+	return json.Marshal(list)
+}
+
+// UnmarshalJSON decodes a table encoded by MarshalJSON. The sockets of a
+// process share a single *Process, as in a table built locally.
+func (t *ProcessTable) UnmarshalJSON(b []byte) error {
+	var list []procInodes
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	m := make(ProcessTable)
+	for _, v := range list {
+		p := &Process{Pid: v.Pid, Name: v.Name, Unit: v.Unit}
+		for _, ino := range v.Inodes {
+			m[ino] = p
+		}
+	}
+	*t = m
+	return nil
+}
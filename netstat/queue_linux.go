@@ -0,0 +1,50 @@
+package netstat
+
+// The tx_queue and rx_queue columns of the socket tables, stored verbatim
+// in TxQueue and RxQueue, are interpreted by the kernel as follows:
+//
+//	TCP, LISTEN   tx_queue: always 0
+//	              rx_queue: connections waiting to be accepted (sk_ack_backlog)
+//	TCP, others   tx_queue: bytes not yet acknowledged by the peer (write_seq - snd_una)
+//	              rx_queue: bytes not yet read by the application (rcv_nxt - copied_seq)
+//	UDP           tx_queue: send buffer memory in use (sk_wmem_alloc)
+//	              rx_queue: receive buffer memory in use (sk_rmem_alloc)
+//
+// The maximum accept backlog of a listener is not exported by /proc.
+
+func (e SockTabEntry) isTCP() bool {
+	return e.Protocol == TCP || e.Protocol == TCP6
+}
+
+func (e SockTabEntry) isTCPListener() bool {
+	return e.isTCP() && e.State == Listen
+}
+
+// SendQueueBytes returns the number of bytes queued for sending, i.e. not
+// yet acknowledged by the peer for TCP. ok is false for TCP listeners, for
+// which the count is meaningless.
+func (e SockTabEntry) SendQueueBytes() (n uint32, ok bool) {
+	if e.isTCPListener() {
+		return 0, false
+	}
+	return e.TxQueue, true
+}
+
+// RecvQueueBytes returns the number of received bytes the application
+// has not read yet. ok is false for TCP listeners, whose receive queue
+// holds connections rather than bytes; see AcceptQueue.
+func (e SockTabEntry) RecvQueueBytes() (n uint32, ok bool) {
+	if e.isTCPListener() {
+		return 0, false
+	}
+	return e.RxQueue, true
+}
+
+// AcceptQueue returns the number of established connections waiting to be
+// accepted by a TCP listener. ok is false for any other socket.
+func (e SockTabEntry) AcceptQueue() (n uint32, ok bool) {
+	if !e.isTCPListener() {
+		return 0, false
+	}
+	return e.RxQueue, true
+}
@@ -0,0 +1,36 @@
+package netstat
+
+import (
+	"fmt"
+	"io"
+)
+
+// RemoteProcessTableCmd is a POSIX shell command printing the owners of
+// the sockets of the host it runs on in the format read by
+// ReadProcessTable. Along with cat /proc/net/tcp and the like it allows
+// collecting socket tables with their owners over e.g. SSH, without
+// installing anything on the target. It must run as root to see the
+// sockets of all users.
+const RemoteProcessTableCmd = `for p in /proc/[0-9]*; do ` +
+	`n=$(cat $p/comm 2>/dev/null) || continue; ` +
+	`for f in $p/fd/*; do l=$(readlink $f 2>/dev/null); ` +
+	`case $l in socket:*) i=${l#socket:\[}; echo "${i%]} ${p#/proc/} $n";; esac; ` +
+	`done; done`
+
+// ParseRemote parses a socket table read from a remote host, e.g. the
+// output of cat /proc/net/tcp piped from an SSH session, and returns the
+// entries satisfying the accept function. proto tells which table was
+// read. If procs is not nil, it is read as the output of
+// RemoteProcessTableCmd run on the same host and the entries are
+// attributed to their owners accordingly; otherwise Process is left nil.
+func ParseRemote(table io.Reader, proto Protocol, procs io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
+	var t ProcessTable
+	if procs != nil {
+		var err error
+		t, err = ReadProcessTable(procs)
+		if err != nil {
+			return nil, fmt.Errorf("netstat: process table: %v", err)
+		}
+	}
+	return ParseSocktab(table, proto, accept, t)
+}
@@ -0,0 +1,98 @@
+package netstat
+
+import (
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// procOwner holds the inodes found to be owned by a process
+type procOwner struct {
+	p      *Process
+	inodes []uint64
+}
+
+// owner returns the process pid and the inodes of want it owns, looked up
+// in /proc unless another lookup has been set
+func (o *scanOpts) owner(pid int, want map[uint64]bool) procOwner {
+	if o.lookup != nil {
+		return o.lookup(pid, want)
+	}
+	base := path.Join("/proc", strconv.Itoa(pid))
+	proc := procFd{base: base, pid: pid, want: want, opts: o}
+	proc.iterFdDir()
+	return procOwner{p: proc.p, inodes: proc.owned}
+}
+
+// resolveConcurrent is the concurrent counterpart of resolveInodes, see
+// WithConcurrentResolve. Workers only ever read want, they send what they
+// find to the caller's goroutine which is the only one to update inodes.
+// Once the deadline is reached they finish the lookup in progress, if any,
+// and stop without starting another one.
+func resolveConcurrent(inodes map[uint64]*Process, want map[uint64]bool, pids []int, opts *scanOpts) {
+	var timeout <-chan time.Time
+	if opts.deadline > 0 {
+		t := time.NewTimer(opts.deadline)
+		defer t.Stop()
+		timeout = t.C
+	}
+	done := make(chan struct{})
+	defer close(done)
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for _, pid := range pids {
+			select {
+			case jobs <- pid:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	results := make(chan procOwner)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pid := range jobs {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				r := opts.owner(pid, want)
+				if len(r.inodes) == 0 {
+					continue
+				}
+				select {
+				case results <- r:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				return
+			}
+			for _, ino := range r.inodes {
+				inodes[ino] = r.p
+			}
+		case <-timeout:
+			opts.logf("netstat: socket owner resolution timed out after %v", opts.deadline)
+			return
+		}
+	}
+}
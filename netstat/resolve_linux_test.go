@@ -0,0 +1,84 @@
+package netstat
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolveConcurrentDeadline(t *testing.T) {
+	base := runtime.NumGoroutine()
+
+	// Odd PIDs own their inode and answer right away, even ones own none,
+	// like most processes, and hang until released. With 3 workers, 1 to
+	// 5 get resolved and 2, 4 and 6 hold all the workers past the
+	// deadline, leaving 7 to 10 to be looked up.
+	release := make(chan struct{})
+	var started int32
+	lookup := func(pid int, want map[uint64]bool) procOwner {
+		atomic.AddInt32(&started, 1)
+		if pid%2 == 0 {
+			<-release
+			return procOwner{}
+		}
+		return procOwner{p: &Process{Pid: pid}, inodes: []uint64{uint64(pid)}}
+	}
+	pids := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	inodes := make(map[uint64]*Process)
+	want := make(map[uint64]bool)
+	for _, pid := range pids {
+		inodes[uint64(pid)] = nil
+		want[uint64(pid)] = true
+	}
+	opts := &scanOpts{
+		workers:  3,
+		deadline: 100 * time.Millisecond,
+		lookup:   lookup,
+	}
+
+	start := time.Now()
+	resolveConcurrent(inodes, want, pids, opts)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("resolution took %v, deadline was %v", d, opts.deadline)
+	}
+	n := atomic.LoadInt32(&started)
+	for _, pid := range pids {
+		p := inodes[uint64(pid)]
+		switch {
+		case pid <= 5 && pid%2 == 1 && (p == nil || p.Pid != pid):
+			t.Errorf("inode %d: got owner %v, want pid %d", pid, p, pid)
+		case (pid > 5 || pid%2 == 0) && p != nil:
+			t.Errorf("inode %d: got owner %v past the deadline, want nil", pid, p)
+		}
+	}
+
+	close(release)
+	for i := 0; runtime.NumGoroutine() > base; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left running, want %d", runtime.NumGoroutine(), base)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m := atomic.LoadInt32(&started); m != n {
+		t.Errorf("%d lookups started after the deadline", m-n)
+	}
+}
+
+func TestResolveConcurrentComplete(t *testing.T) {
+	lookup := func(pid int, want map[uint64]bool) procOwner {
+		return procOwner{p: &Process{Pid: pid}, inodes: []uint64{uint64(pid)}}
+	}
+	pids := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	inodes := make(map[uint64]*Process)
+	want := make(map[uint64]bool)
+	for _, pid := range pids {
+		want[uint64(pid)] = true
+	}
+	resolveConcurrent(inodes, want, pids, &scanOpts{workers: 3, lookup: lookup})
+	for _, pid := range pids {
+		if p := inodes[uint64(pid)]; p == nil || p.Pid != pid {
+			t.Errorf("inode %d: got owner %v, want pid %d", pid, p, pid)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package netstat
+
+// Role tells which end of a connection the local socket is
+type Role uint8
+
+// Socket roles
+const (
+	RoleUnknown Role = iota
+	RoleServer
+	RoleClient
+)
+
+var roleNames = [...]string{
+	"unknown",
+	"server",
+	"client",
+}
+
+func (r Role) String() string {
+	if int(r) >= len(roleNames) {
+		return roleNames[RoleUnknown]
+	}
+	return roleNames[r]
+}
+
+// transport returns the IPv4 counterpart of p, so that tcp and tcp6 (or
+// udp and udp6) entries compare equal
+func transport(p Protocol) Protocol {
+	switch p {
+	case TCP6:
+		return TCP
+	case UDP6:
+		return UDP
+	}
+	return p
+}
+
+// bindCovers reports whether a socket bound to the address b receives the
+// traffic destined to the address a, taking wildcard binds into account. A
+// wildcard IPv6 bind covers IPv4 addresses as well, as dual stack sockets
+// are the default.
+func bindCovers(b, a *SockAddr) bool {
+	if b.Port != a.Port {
+		return false
+	}
+	switch {
+	case b.IP.Equal(a.IP):
+		return true
+	case b.IP.To4() != nil && b.IP.IsUnspecified():
+		return a.IP.To4() != nil
+	case b.IP.IsUnspecified():
+		return true
+	}
+	return false
+}
+
+// Role guesses whether e is the server or the client end of a connection
+// by looking its local address up in listeners, the TCP listeners and
+// unconnected UDP sockets of a scan. Connections accepted by one of the
+// listeners, which share its local port and either its address or the
+// address family of a wildcard bind, are server ends; the others are
+// assumed to have been initiated locally from an ephemeral port. Listeners
+// themselves, such as a DNS server's UDP socket bound to port 53, are
+// always servers.
+func (e SockTabEntry) Role(listeners []SockTabEntry) Role {
+	if e.LocalAddr == nil {
+		return RoleUnknown
+	}
+	if isBound(&e) {
+		return RoleServer
+	}
+	for _, l := range listeners {
+		if !isBound(&l) || transport(l.Protocol) != transport(e.Protocol) {
+			continue
+		}
+		if l.LocalAddr != nil && bindCovers(l.LocalAddr, e.LocalAddr) {
+			return RoleServer
+		}
+	}
+	return RoleClient
+}
+
+// LocalCall is a connection between two processes of the same host
+type LocalCall struct {
+	Client SockTabEntry
+	Server SockTabEntry
+}
+
+// isLocalPeer reports whether the remote end of e is on the same host,
+// either through loopback or through one of the host's own addresses
+func isLocalPeer(e *SockTabEntry) bool {
+	ip := e.RemoteAddr.IP
+	return ip.IsLoopback() || normIP(ip).Equal(normIP(e.LocalAddr.IP))
+}
+
+// LocalCallGraph links the client ends of the host local connections in
+// s, e.g. an application connected to a proxy over loopback, to the server
+// end. The server is the connection accepted on the other side if s holds
+// it, which tells the right process when listeners hand connections over
+// to workers, and the listener otherwise. s should thus be a full scan of
+// both the IPv4 and the IPv6 tables, listeners included, with process
+// information resolved.
+func LocalCallGraph(s []SockTabEntry) []LocalCall {
+	var listeners []SockTabEntry
+	accepted := make(map[string]int)
+	for i, e := range s {
+		if isBound(&e) {
+			listeners = append(listeners, e)
+		} else if e.LocalAddr != nil && e.RemoteAddr != nil {
+			k := transport(e.Protocol).String() + " " + addrKey(e.LocalAddr) + "-" + addrKey(e.RemoteAddr)
+			accepted[k] = i
+		}
+	}
+	var calls []LocalCall
+	for _, e := range s {
+		if e.State != Established || e.LocalAddr == nil || e.RemoteAddr == nil {
+			continue
+		}
+		if !isLocalPeer(&e) || e.Role(listeners) != RoleClient {
+			continue
+		}
+		peer := transport(e.Protocol).String() + " " + addrKey(e.RemoteAddr) + "-" + addrKey(e.LocalAddr)
+		if i, ok := accepted[peer]; ok {
+			calls = append(calls, LocalCall{Client: e, Server: s[i]})
+			continue
+		}
+		for _, l := range listeners {
+			if transport(l.Protocol) == transport(e.Protocol) && bindCovers(l.LocalAddr, e.RemoteAddr) {
+				calls = append(calls, LocalCall{Client: e, Server: l})
+				break
+			}
+		}
+	}
+	return calls
+}
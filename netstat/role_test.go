@@ -0,0 +1,32 @@
+package netstat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRole(t *testing.T) {
+	addr := func(ip string, port uint16) *SockAddr {
+		return &SockAddr{IP: net.ParseIP(ip), Port: port}
+	}
+	dns := SockTabEntry{Protocol: UDP, State: Close, LocalAddr: addr("0.0.0.0", 53), RemoteAddr: addr("0.0.0.0", 0)}
+	web := SockTabEntry{Protocol: TCP6, State: Listen, LocalAddr: addr("::", 443), RemoteAddr: addr("::", 0)}
+	listeners := []SockTabEntry{dns, web}
+	tests := []struct {
+		name string
+		e    SockTabEntry
+		want Role
+	}{
+		{"udp bound", dns, RoleServer},
+		{"tcp listener", web, RoleServer},
+		{"udp connected to bound port", SockTabEntry{Protocol: UDP, State: Established, LocalAddr: addr("10.0.0.5", 53), RemoteAddr: addr("10.0.0.9", 41000)}, RoleServer},
+		{"udp client", SockTabEntry{Protocol: UDP, State: Established, LocalAddr: addr("10.0.0.5", 41000), RemoteAddr: addr("8.8.8.8", 53)}, RoleClient},
+		{"tcp accepted", SockTabEntry{Protocol: TCP6, State: Established, LocalAddr: addr("::ffff:10.0.0.5", 443), RemoteAddr: addr("::ffff:10.0.0.9", 52000)}, RoleServer},
+		{"tcp client", SockTabEntry{Protocol: TCP, State: Established, LocalAddr: addr("10.0.0.5", 52000), RemoteAddr: addr("10.0.0.9", 53)}, RoleClient},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Role(listeners); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
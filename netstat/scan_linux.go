@@ -0,0 +1,296 @@
+package netstat
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+type scanOpts struct {
+	want   stateFilter
+	unit   bool
+	max    int
+	procs  ProcessTable
+	noProc bool
+	exUIDs map[uint32]bool
+	parse  LineParser
+	raw    bool
+	family bool
+	log    func(format string, args ...interface{})
+
+	workers  int
+	deadline time.Duration
+	lookup   func(pid int, want map[uint64]bool) procOwner // nil for /proc
+}
+
+// wantLine reports whether a socket table line passes the state and UID
+// filters. Only the relevant columns are looked at, so lines can be
+// dismissed before being split into fields.
+func (o *scanOpts) wantLine(line []byte) bool {
+	if o.want != nil {
+		st, ok := parseHexByte(field(line, fieldState))
+		if ok && !o.want(knownState(st)) {
+			return false
+		}
+	}
+	if o.exUIDs != nil {
+		uid, ok := parseDec(field(line, fieldUID))
+		if ok && o.exUIDs[uid] {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *scanOpts) logf(format string, args ...interface{}) {
+	if o.log != nil {
+		o.log(format, args...)
+	}
+}
+
+// Option configures optional behaviour of Scan
+type Option func(*scanOpts)
+
+// WithUnit makes Scan resolve the systemd unit (e.g. nginx.service) each
+// socket owner belongs to and store it in Process.Unit. The unit is
+// derived from the cgroup of the process, so it is left empty on hosts
+// which are not managed by systemd.
+func WithUnit() Option {
+	return func(o *scanOpts) { o.unit = true }
+}
+
+// WithMaxEntries bounds the number of entries collected by Scan to n. Once
+// the limit is hit, Scan stops reading the socket table and returns the
+// first n entries together with ErrTruncated. A value of n <= 0 means no
+// limit.
+func WithMaxEntries(n int) Option {
+	return func(o *scanOpts) { o.max = n }
+}
+
+// WithProcessTable makes Scan attribute sockets to their owners by looking
+// up their inodes in t instead of walking /proc. Sockets whose inode is
+// not in t are left without a Process.
+func WithProcessTable(t ProcessTable) Option {
+	return func(o *scanOpts) { o.procs = t }
+}
+
+// WithLogger makes Scan report the problems it otherwise silently works
+// around, such as processes whose sockets could not be inspected, through
+// logf. By default nothing is logged.
+func WithLogger(logf func(format string, args ...interface{})) Option {
+	return func(o *scanOpts) { o.log = logf }
+}
+
+// WithConcurrentResolve makes Scan spread the walk of /proc resolving
+// socket owners over workers goroutines and give up on it once timeout has
+// elapsed, if timeout is positive. Entries whose owner has not been found
+// in time are left with a nil Process, so that on hosts with very many
+// processes a scan completes in bounded time with best effort attribution.
+// The logger set with WithLogger may then be called concurrently, and
+// still be called by the lookups in progress at the deadline after Scan
+// has returned; no lookup is started past the deadline though.
+func WithConcurrentResolve(workers int, timeout time.Duration) Option {
+	return func(o *scanOpts) {
+		if workers < 1 {
+			workers = 1
+		}
+		o.workers = workers
+		o.deadline = timeout
+	}
+}
+
+// WithoutProcesses makes Scan skip the resolution of socket owners, leaving
+// Process nil in all the entries. It is meant for listing sockets cheaply,
+// owners of the entries of interest can then be resolved afterwards with
+// ResolveProcesses.
+func WithoutProcesses() Option {
+	return func(o *scanOpts) { o.noProc = true }
+}
+
+func newScanOpts(opts []Option) *scanOpts {
+	var o scanOpts
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return &o
+}
+
+// WithStates makes Scan only collect the sockets in one of states. Lines
+// in other states are skipped after reading their state column alone,
+// which saves most of the parsing when e.g. only LISTEN and ESTABLISHED
+// sockets matter on a host with many TIME_WAIT ones.
+func WithStates(states ...SkState) Option {
+	return func(o *scanOpts) {
+		set := make(map[SkState]bool, len(states))
+		for _, st := range states {
+			set[st] = true
+		}
+		o.want = func(st SkState) bool { return set[st] }
+	}
+}
+
+// WithExcludeUIDs makes Scan leave out the sockets owned by any of uids,
+// e.g. 0 to hide the sockets of system services. Lines of excluded users
+// are skipped before being parsed and their owners are never resolved.
+func WithExcludeUIDs(uids ...uint32) Option {
+	return func(o *scanOpts) {
+		if o.exUIDs == nil {
+			o.exUIDs = make(map[uint32]bool, len(uids))
+		}
+		for _, uid := range uids {
+			o.exUIDs[uid] = true
+		}
+	}
+}
+
+// WithRawAddrs makes Scan keep the hexadecimal addresses the entries were
+// parsed from in LocalAddrRaw and RemoteAddrRaw, e.g. 0100007F:0050 for
+// 127.0.0.1:80. It is meant for diagnosing byte order issues.
+func WithRawAddrs() Option {
+	return func(o *scanOpts) { o.raw = true }
+}
+
+// WithFamilyCheck makes Scan verify that the addresses parsed from the tcp
+// and udp tables are 4 bytes long and those from the tcp6 and udp6 tables
+// 16 bytes long, and fail otherwise. A mismatch means a table was parsed
+// the wrong way, so this is meant as a self check, e.g. along with
+// WithLineParser, rather than for regular scans.
+func WithFamilyCheck() Option {
+	return func(o *scanOpts) { o.family = true }
+}
+
+// LineParser parses the white space separated fields of a socket table
+// line into an entry
+type LineParser func(fields []string) (SockTabEntry, error)
+
+// WithLineParser makes Scan parse socket table lines with fn instead of the
+// built-in parser, for kernels whose tables have a layout the package does
+// not know about or to extract additional columns. The title line is still
+// skipped, and entries whose Protocol is left zero are set the scanned one.
+// Errors returned by fn abort the scan. Note that WithExcludeUIDs and
+// other line filters still look at the built-in column positions.
+func WithLineParser(fn LineParser) Option {
+	return func(o *scanOpts) { o.parse = fn }
+}
+
+// Scan returns a slice of the sockets in the proto table that satisfy the
+// accept function, configured by opts
+func Scan(proto Protocol, accept AcceptFn, opts ...Option) ([]SockTabEntry, error) {
+	return doNetstat(proto, newScanOpts(opts), accept)
+}
+
+// ResolveProcesses sets the Process of the entries in s, typically a subset
+// of a scan made with WithoutProcesses. /proc is walked once and only the
+// inodes of s are looked for. Options affecting process resolution, such
+// as WithUnit or WithProcessTable, are honored.
+func ResolveProcesses(s []SockTabEntry, opts ...Option) {
+	extractProcInfo(s, newScanOpts(opts))
+}
+
+// BuildProcessTable walks /proc once and returns the owners of all the
+// sockets currently found in the TCP and UDP tables. The table can be
+// saved, sent along with dumps of the socket tables, or applied to further
+// scans made with WithoutProcesses, sparing a walk of /proc each time.
+// Options affecting process resolution, such as WithUnit or
+// WithConcurrentResolve, are honored.
+func BuildProcessTable(opts ...Option) (ProcessTable, error) {
+	o := newScanOpts(opts)
+	inodes := make(map[uint64]*Process)
+	err := eachInode(func(_ Protocol, ino uint64) {
+		inodes[ino] = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolveInodes(inodes, o)
+	t := make(ProcessTable, len(inodes))
+	for ino, p := range inodes {
+		if p != nil {
+			t[ino] = p
+		}
+	}
+	return t, nil
+}
+
+var unitSuffixes = [...]string{
+	".service",
+	".scope",
+	".slice",
+	".socket",
+	".mount",
+	".swap",
+}
+
+func isUnitName(s string) bool {
+	for _, suf := range unitSuffixes {
+		if strings.HasSuffix(s, suf) && len(s) > len(suf) {
+			return true
+		}
+	}
+	return false
+}
+
+// unitFromCgroup returns the innermost systemd unit of a cgroup path such
+// as /user.slice/user-1000.slice/session-2.scope
+func unitFromCgroup(cg string) string {
+	elems := strings.Split(cg, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if isUnitName(elems[i]) {
+			return elems[i]
+		}
+	}
+	return ""
+}
+
+// procUnit reads the cgroup membership of the process rooted at base and
+// returns its systemd unit. The unified hierarchy (cgroup v2) and the
+// name=systemd hierarchy (cgroup v1) are both recognized.
+func procUnit(base string) string {
+	f, err := os.Open(path.Join(base, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var unit string
+	br := bufio.NewScanner(f)
+	for br.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(br.Text(), ":", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[1] {
+		case "name=systemd":
+			return unitFromCgroup(fields[2])
+		case "":
+			if unit == "" {
+				unit = unitFromCgroup(fields[2])
+			}
+		}
+	}
+	return unit
+}
+
+// DiffAgainstFile scans the proto table and compares it against the
+// snapshot previously saved to the named file with WriteSnapshot. See Diff
+// for how entries are matched.
+func DiffAgainstFile(name string, proto Protocol) (added, removed []SockTabEntry, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	old, err := ReadSnapshot(f)
+	f.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	cur, err := Scan(proto, NoopFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	added, removed = Diff(old, cur)
+	return added, removed, nil
+}
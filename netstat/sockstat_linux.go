@@ -0,0 +1,106 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	pathSockStat  = "/proc/net/sockstat"
+	pathSockStat6 = "/proc/net/sockstat6"
+)
+
+// TCPStats holds the totals of the TCP line of /proc/net/sockstat
+type TCPStats struct {
+	InUse    int
+	Orphan   int
+	TimeWait int
+	Alloc    int
+	Mem      int // pages
+}
+
+// UDPStats holds the totals of the UDP line of /proc/net/sockstat
+type UDPStats struct {
+	InUse int
+	Mem   int // pages
+}
+
+// SockStats holds the aggregate socket counts of /proc/net/sockstat and
+// /proc/net/sockstat6
+type SockStats struct {
+	Used      int // sockets of all families
+	TCP       TCPStats
+	UDP       UDPStats
+	TCP6InUse int
+	UDP6InUse int
+}
+
+// parseSockStat parses lines of the form
+//
+//	TCP: inuse 4 orphan 0 tw 0 alloc 4 mem 0
+//
+// into a map keyed by protocol and then by counter name
+func parseSockStat(r io.Reader) (map[string]map[string]int, error) {
+	m := make(map[string]map[string]int)
+	br := bufio.NewScanner(r)
+	for br.Scan() {
+		fields := strings.Fields(br.Text())
+		if len(fields) < 1 || len(fields)%2 == 0 {
+			return nil, fmt.Errorf("netstat: bad formatted line: %v", br.Text())
+		}
+		counters := make(map[string]int)
+		for i := 1; i < len(fields); i += 2 {
+			v, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, err
+			}
+			counters[fields[i]] = v
+		}
+		m[strings.TrimSuffix(fields[0], ":")] = counters
+	}
+	return m, br.Err()
+}
+
+func readSockStat(path string) (map[string]map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSockStat(f)
+}
+
+// SockStat returns the socket usage totals maintained by the kernel. It is
+// much cheaper than enumerating the sockets when only the numbers matter.
+// The IPv6 counts are left zero on hosts without IPv6 support.
+func SockStat() (*SockStats, error) {
+	m, err := readSockStat(pathSockStat)
+	if err != nil {
+		return nil, err
+	}
+	m6, err := readSockStat(pathSockStat6)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	tcp, udp := m["TCP"], m["UDP"]
+	return &SockStats{
+		Used: m["sockets"]["used"],
+		TCP: TCPStats{
+			InUse:    tcp["inuse"],
+			Orphan:   tcp["orphan"],
+			TimeWait: tcp["tw"],
+			Alloc:    tcp["alloc"],
+			Mem:      tcp["mem"],
+		},
+		UDP: UDPStats{
+			InUse: udp["inuse"],
+			Mem:   udp["mem"],
+		},
+		TCP6InUse: m6["TCP6"]["inuse"],
+		UDP6InUse: m6["UDP6"]["inuse"],
+	}, nil
+}
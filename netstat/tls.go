@@ -0,0 +1,120 @@
+package netstat
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultProbeTimeout bounds each probe when ProbeTLS is given no
+	// positive timeout
+	defaultProbeTimeout = 2 * time.Second
+
+	// maxProbes is the number of listeners ProbeTLS probes at once
+	maxProbes = 8
+)
+
+// CertSummary describes the leaf certificate served by a TLS listener
+type CertSummary struct {
+	Subject  string
+	DNSNames []string
+	IPs      []net.IP
+	NotAfter time.Time
+}
+
+// probeAddr returns the address to dial to reach the listener bound to a
+func probeAddr(a *SockAddr) string {
+	ip := normIP(a.IP)
+	if ip.IsUnspecified() {
+		if len(ip) == net.IPv4len {
+			ip = net.IPv4(127, 0, 0, 1)
+		} else {
+			ip = net.IPv6loopback
+		}
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(a.Port)))
+}
+
+// probeCert completes a TLS handshake with addr within timeout, or until
+// ctx is done, and summarizes the leaf certificate served
+func probeCert(ctx context.Context, addr string, timeout time.Duration) (*CertSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer raw.Close()
+	// Abort the handshake, e.g. with a peer which never answers the
+	// ClientHello, once ctx is done
+	go func() {
+		<-ctx.Done()
+		raw.Close()
+	}()
+	conn := tls.Client(raw, &tls.Config{
+		// Only the certificate is of interest, not whether it is trusted
+		InsecureSkipVerify: true,
+	})
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, nil
+	}
+	c := certs[0]
+	return &CertSummary{
+		Subject:  c.Subject.String(),
+		DNSNames: c.DNSNames,
+		IPs:      c.IPAddresses,
+		NotAfter: c.NotAfter,
+	}, nil
+}
+
+// ProbeTLS connects to each TCP listener in s and, if it completes a TLS
+// handshake, stores a summary of the certificate it serves in Cert.
+// Listeners bound to a wildcard address are reached through loopback and
+// the certificates are not verified. Each probe, connection and handshake
+// included, is bounded by timeout, or 2 seconds if timeout is not
+// positive. Up to 8 listeners are probed at once, so that ProbeTLS takes
+// at most ceil(n/8) times timeout for n listeners; it returns earlier,
+// with the error of ctx, once ctx is done, in which case the listeners
+// not probed yet are left without Cert. Note that this opens real
+// connections to the local services, which may show up in their logs.
+func ProbeTLS(ctx context.Context, s []SockTabEntry, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	sem := make(chan struct{}, maxProbes)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := range s {
+		e := &s[i]
+		if e.State != Listen || transport(e.Protocol) != TCP || e.LocalAddr == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			cert, err := probeCert(ctx, probeAddr(e.LocalAddr), timeout)
+			if err == nil {
+				e.Cert = cert
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
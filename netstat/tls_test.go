@@ -0,0 +1,68 @@
+package netstat
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func listenEntry(addr net.Addr) SockTabEntry {
+	a := addr.(*net.TCPAddr)
+	return SockTabEntry{
+		Protocol:  TCP,
+		State:     Listen,
+		LocalAddr: &SockAddr{IP: a.IP, Port: uint16(a.Port)},
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	s := []SockTabEntry{listenEntry(srv.Listener.Addr())}
+	if err := ProbeTLS(context.Background(), s, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if s[0].Cert == nil {
+		t.Fatal("no certificate found")
+	}
+}
+
+func TestProbeTLSSilentPeer(t *testing.T) {
+	// Accepts connections and never answers the ClientHello
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	s := make([]SockTabEntry, 20)
+	for i := range s {
+		s[i] = listenEntry(ln.Addr())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = ProbeTLS(ctx, s, 0)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("ProbeTLS took %v past a 200ms deadline", d)
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	for i := range s {
+		if s[i].Cert != nil {
+			t.Errorf("entry %d: got certificate from a silent peer", i)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const pathUnixTab = "/proc/net/unix"
+
+// soAcceptCon is the flag of listening unix sockets
+const soAcceptCon = 1 << 16
+
+// UnixSockEntry type represents each line of the /proc/net/unix
+type UnixSockEntry struct {
+	Inode     uint64
+	Type      int // SOCK_STREAM, SOCK_DGRAM or SOCK_SEQPACKET
+	State     int // SS_UNCONNECTED, SS_CONNECTING, SS_CONNECTED, ...
+	Listening bool
+	Path      string // empty for unnamed, prefixed by @ for abstract sockets
+	Process   *Process
+}
+
+// columns of the /proc/net/unix:
+//
+//	Num RefCount Protocol Flags Type St Inode Path
+const (
+	fieldUnixFlags = 3
+	fieldUnixType  = 4
+	fieldUnixState = 5
+	fieldUnixInode = 6
+	fieldUnixPath  = 7
+	minUnixFields  = 7
+)
+
+func parseUnixTab(r io.Reader) ([]UnixSockEntry, error) {
+	br := bufio.NewScanner(r)
+	var tab []UnixSockEntry
+
+	// Discard title
+	br.Scan()
+
+	for br.Scan() {
+		var e UnixSockEntry
+		fields := strings.Fields(br.Text())
+		if len(fields) < minUnixFields {
+			return nil, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
+		}
+		flags, err := strconv.ParseUint(fields[fieldUnixFlags], 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		e.Listening = flags&soAcceptCon != 0
+		u, err := strconv.ParseUint(fields[fieldUnixType], 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		e.Type = int(u)
+		u, err = strconv.ParseUint(fields[fieldUnixState], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		e.State = int(u)
+		e.Inode, err = strconv.ParseUint(fields[fieldUnixInode], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > fieldUnixPath {
+			e.Path = strings.Join(fields[fieldUnixPath:], " ")
+		}
+		tab = append(tab, e)
+	}
+	return tab, br.Err()
+}
+
+func readUnixTab() ([]UnixSockEntry, error) {
+	f, err := os.Open(pathUnixTab)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseUnixTab(f)
+}
+
+// UnixSocks returns a slice of the unix domain sockets, along with the
+// processes owning them
+func UnixSocks() ([]UnixSockEntry, error) {
+	tab, err := readUnixTab()
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[uint64]*Process, len(tab))
+	for i := range tab {
+		inodes[tab[i].Inode] = nil
+	}
+	resolveInodes(inodes, &scanOpts{})
+	for i := range tab {
+		tab[i].Process = inodes[tab[i].Inode]
+	}
+	return tab, nil
+}